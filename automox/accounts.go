@@ -0,0 +1,95 @@
+package automox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// AccountsService is an interface for interacting with the account scoped
+// endpoints of the Automox API
+type AccountsService interface {
+	ListZones(context.Context) (Zones, error)
+	ListUsers(context.Context) (AccountUsers, error)
+	Invite(context.Context, AccountInvitation) (*Invitation, error)
+	RemoveFromZone(context.Context, string, string) error
+}
+
+// AccountsClient facilitates requests with the Automox account endpoints
+type AccountsClient struct {
+	client *Client
+}
+
+// ListZones lists every zone in the account
+func (c *AccountsClient) ListZones(ctx context.Context) (Zones, error) {
+	path, err := c.client.accountPath("/zones")
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.client.newRequest(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Zones{}
+	if _, err := c.client.makeRequest(req, res); err != nil {
+		return nil, err
+	}
+	return *res, nil
+}
+
+// ListUsers lists every user in the account along with their zone roles
+func (c *AccountsClient) ListUsers(ctx context.Context) (AccountUsers, error) {
+	path, err := c.client.accountPath("/users")
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.client.newRequest(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &AccountUsers{}
+	if _, err := c.client.makeRequest(req, res); err != nil {
+		return nil, err
+	}
+	return *res, nil
+}
+
+// Invite invites a user to the account and the zones in the invitation
+func (c *AccountsClient) Invite(ctx context.Context, inv AccountInvitation) (*Invitation, error) {
+	path, err := c.client.accountPath("/invitations")
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.client.newRequest(ctx, http.MethodPost, path, nil, inv)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Invitation{}
+	if _, err := c.client.makeRequest(req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RemoveFromZone removes the user with the given ID from a zone, leaving
+// their membership of the account and other zones untouched
+func (c *AccountsClient) RemoveFromZone(ctx context.Context, zoneID, userID string) error {
+	path, err := c.client.accountPath(fmt.Sprintf("/zones/%s/users/%s", zoneID, userID))
+	if err != nil {
+		return err
+	}
+
+	req, err := c.client.newRequest(ctx, http.MethodDelete, path, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.makeRequest(req, nil)
+	return err
+}
@@ -0,0 +1,60 @@
+package automox
+
+type Zones []Zone
+
+// Zone is an organization within an Automox account
+type Zone struct {
+	ID             string      `json:"id"`
+	OrganizationID int64       `json:"organization_id"`
+	ParentID       string      `json:"parent_id"`
+	Name           string      `json:"name"`
+	DeviceCount    int         `json:"device_count"`
+	CreatedAt      AutomoxTime `json:"created_at"`
+	UpdatedAt      AutomoxTime `json:"updated_at"`
+}
+
+type AccountUsers []AccountUser
+
+// AccountUser is a user of an Automox account and the roles they hold in
+// each zone
+type AccountUser struct {
+	ID              string           `json:"id"`
+	FirstName       string           `json:"first_name"`
+	LastName        string           `json:"last_name"`
+	Email           string           `json:"email"`
+	AccountRBACRole string           `json:"account_rbac_role"`
+	Zones           []ZoneAssignment `json:"zones"`
+}
+
+// ZoneAssignment grants an RBAC role within a single zone
+type ZoneAssignment struct {
+	ZoneID   string `json:"zone_id"`
+	RBACRole string `json:"rbac_role"`
+}
+
+// AccountInvitation invites a user by email to the account and any zones
+type AccountInvitation struct {
+	Email           string           `json:"email"`
+	AccountRBACRole string           `json:"account_rbac_role"`
+	Zones           []ZoneAssignment `json:"zone_assignments"`
+}
+
+// Invitation is a pending invitation to join an account
+type Invitation struct {
+	ID              string           `json:"id"`
+	Email           string           `json:"email"`
+	AccountRBACRole string           `json:"account_rbac_role"`
+	Zones           []ZoneAssignment `json:"zone_assignments"`
+	ExpiresAt       AutomoxTime      `json:"expires_at"`
+}
+
+// RoleIn returns the user's RBAC role in the given zone, or an empty string
+// if they have not been assigned to it
+func (u AccountUser) RoleIn(zoneID string) string {
+	for _, z := range u.Zones {
+		if z.ZoneID == zoneID {
+			return z.RBACRole
+		}
+	}
+	return ""
+}
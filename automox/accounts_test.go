@@ -0,0 +1,90 @@
+package automox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAccountsNotScopedToOrg(t *testing.T) {
+	type request struct {
+		method, path, query, body string
+	}
+	var got []request
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = append(got, request{r.Method, r.URL.Path, r.URL.RawQuery, string(b)})
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `[]`)
+		case http.MethodPost:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"id":"inv-1","email":"a@example.com"}`)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}, WithOrgID(42), WithAccountID("acct-1"))
+	ctx := context.Background()
+	accounts := am.Accounts()
+
+	if _, err := accounts.ListZones(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := accounts.ListUsers(ctx); err != nil {
+		t.Fatal(err)
+	}
+	inv, err := accounts.Invite(ctx, AccountInvitation{Email: "a@example.com", AccountRBACRole: "no-global-access"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.ID != "inv-1" {
+		t.Errorf("invitation = %+v", inv)
+	}
+	if err := accounts.RemoveFromZone(ctx, "zone-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	// Organization scoped routes still carry the organization
+	if _, err := am.Do(ctx, http.MethodGet, "/api/servers", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []request{
+		{http.MethodGet, "/api/accounts/acct-1/zones", "", ""},
+		{http.MethodGet, "/api/accounts/acct-1/users", "", ""},
+		{http.MethodPost, "/api/accounts/acct-1/invitations", "", `{"email":"a@example.com","account_rbac_role":"no-global-access","zone_assignments":null}`},
+		{http.MethodDelete, "/api/accounts/acct-1/zones/zone-1/users/user-1", "", ""},
+		{http.MethodGet, "/api/servers", "o=42", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("requests:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestAccountsRequireAccountID(t *testing.T) {
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request sent without an account ID: %s", r.URL)
+	}, WithOrgID(42))
+
+	_, err := am.Accounts().ListZones(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "account ID") {
+		t.Errorf("error = %v, want the account ID to be missing", err)
+	}
+}
+
+func TestAccountUserRoleIn(t *testing.T) {
+	var u AccountUser
+	if err := json.Unmarshal([]byte(`{"id":"u","zones":[{"zone_id":"z1","rbac_role":"zone-admin"}]}`), &u); err != nil {
+		t.Fatal(err)
+	}
+	if got := u.RoleIn("z1"); got != "zone-admin" {
+		t.Errorf("RoleIn(z1) = %q", got)
+	}
+	if got := u.RoleIn("z2"); got != "" {
+		t.Errorf("RoleIn(z2) = %q, want none", got)
+	}
+}
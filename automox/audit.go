@@ -0,0 +1,171 @@
+package automox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rk295/go-automox/automox/internal/shared"
+)
+
+const (
+	auditEventsURL = "/api/audit-service/v1/orgs/%s/events"
+	auditDateFmt   = "2006-01-02"
+	auditPageSize  = 500
+)
+
+// AuditService is an interface for interacting with the audit service
+// endpoints of the Automox API
+type AuditService interface {
+	List(context.Context, AuditFilter) (AuditEvents, error)
+	Events(context.Context, AuditFilter) *AuditIterator
+}
+
+// AuditClient facilitates requests with the Automox audit service
+type AuditClient struct {
+	client *Client
+}
+
+// List returns every audit event matching the filter. For long ranges
+// prefer Events, which does not hold every event in memory.
+func (c *AuditClient) List(ctx context.Context, f AuditFilter) (AuditEvents, error) {
+	var events AuditEvents
+	it := c.Events(ctx, f)
+	for it.Next() {
+		events = append(events, it.Event())
+	}
+	return events, it.Err()
+}
+
+// Events returns an iterator over the audit events matching the filter,
+// fetching further pages and days from the API as it is advanced
+func (c *AuditClient) Events(ctx context.Context, f AuditFilter) *AuditIterator {
+	start := f.Start
+	if start.IsZero() {
+		start = time.Now()
+	}
+	end := f.End
+	if end.IsZero() {
+		end = start
+	}
+
+	it := &AuditIterator{
+		ctx:    ctx,
+		client: c,
+		filter: f,
+		day:    shared.TruncateDay(start),
+		end:    shared.TruncateDay(end),
+	}
+	it.done = it.day.After(it.end)
+	return it
+}
+
+// page fetches a single page of events for one day
+func (c *AuditClient) page(ctx context.Context, f AuditFilter, day time.Time, cursor string) (*auditEventsPage, error) {
+	if c.client.orgUUID == "" {
+		return nil, missingOptionErr("organization UUID")
+	}
+
+	q := url.Values{}
+	q.Set("date", day.Format(auditDateFmt))
+	q.Set("limit", fmt.Sprint(auditPageSize))
+	if f.Actor != "" {
+		q.Set("actor_email", f.Actor)
+	}
+	if f.Action != "" {
+		q.Set("type_name", f.Action)
+	}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+
+	req, err := c.client.newRequest(ctx, http.MethodGet, fmt.Sprintf(auditEventsURL, c.client.orgUUID), q, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &auditEventsPage{}
+	if _, err := c.client.makeRequest(req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// AuditIterator walks audit events page by page and day by day. Use it in
+// the same way as a bufio.Scanner:
+//
+//	it := api.Audit().Events(ctx, filter)
+//	for it.Next() {
+//		e := it.Event()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type AuditIterator struct {
+	ctx    context.Context
+	client *AuditClient
+	filter AuditFilter
+	day    time.Time
+	end    time.Time
+	cursor string
+	done   bool // no pages are left to fetch
+	buf    AuditEvents
+	cur    AuditEvent
+	err    error
+}
+
+func init() {
+	shared.NewAuditIterator = newAuditIterator
+}
+
+// newAuditIterator returns an iterator over a fixed list of events which
+// then stops with err, if not nil. It lets fakes of AuditService return an
+// iterator without the API.
+func newAuditIterator(events AuditEvents, err error) *AuditIterator {
+	return &AuditIterator{
+		buf:  append(AuditEvents(nil), events...),
+		err:  err,
+		done: true,
+	}
+}
+
+// Next advances to the next event, returning false once every event has
+// been read or an error occurs
+func (it *AuditIterator) Next() bool {
+	for len(it.buf) == 0 {
+		if it.err != nil || it.done {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+
+		page, err := it.client.page(it.ctx, it.filter, it.day, it.cursor)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		it.buf = page.Data
+		it.cursor = page.Metadata.Next
+		if it.cursor == "" {
+			it.day = it.day.AddDate(0, 0, 1)
+			it.done = it.day.After(it.end)
+		}
+	}
+
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+// Event returns the event most recently read by Next
+func (it *AuditIterator) Event() AuditEvent {
+	return it.cur
+}
+
+// Err returns the first error encountered while iterating, if any
+func (it *AuditIterator) Err() error {
+	return it.err
+}
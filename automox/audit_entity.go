@@ -0,0 +1,53 @@
+package automox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditEvents []AuditEvent
+
+// AuditEvent is a single console or API action recorded by the Automox
+// audit service
+type AuditEvent struct {
+	ID       string          `json:"id"`
+	Time     time.Time       `json:"time"`
+	Action   string          `json:"action"`
+	Message  string          `json:"message"`
+	Actor    AuditActor      `json:"actor"`
+	Target   AuditTarget     `json:"target"`
+	Before   json.RawMessage `json:"before"`
+	After    json.RawMessage `json:"after"`
+	SourceIP string          `json:"source_ip"`
+}
+
+type AuditActor struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+}
+
+type AuditTarget struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// AuditFilter narrows the audit events returned. Start and End are
+// inclusive calendar days in UTC; a zero End means just the Start day and a
+// zero Start means today.
+type AuditFilter struct {
+	Start  time.Time
+	End    time.Time
+	Actor  string
+	Action string
+}
+
+// auditEventsPage is the cursor paged envelope returned by the audit service
+type auditEventsPage struct {
+	Data     AuditEvents `json:"data"`
+	Metadata struct {
+		Next string `json:"next"`
+	} `json:"metadata"`
+}
@@ -0,0 +1,138 @@
+package automox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// auditPages serves audit events keyed by date then cursor, recording the
+// date and cursor of each request
+type auditPages struct {
+	pages map[string]map[string]auditEventsPage
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func (a *auditPages) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	date, cursor := q.Get("date"), q.Get("cursor")
+	a.mu.Lock()
+	a.requests = append(a.requests, date+"/"+cursor)
+	a.mu.Unlock()
+
+	page, ok := a.pages[date][cursor]
+	if !ok {
+		page = auditEventsPage{Data: AuditEvents{}}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+func auditPage(next string, ids ...string) auditEventsPage {
+	p := auditEventsPage{Data: AuditEvents{}}
+	for _, id := range ids {
+		p.Data = append(p.Data, AuditEvent{ID: id})
+	}
+	p.Metadata.Next = next
+	return p
+}
+
+func TestAuditEventsPaging(t *testing.T) {
+	srv := &auditPages{pages: map[string]map[string]auditEventsPage{
+		"2026-01-30": {"": auditPage("c1", "a", "b"), "c1": auditPage("c2"), "c2": auditPage("", "c")},
+		"2026-01-31": {"": auditPage("")},
+		"2026-02-01": {"": auditPage("", "d")},
+	}}
+	am := newTestClient(t, srv.ServeHTTP, WithOrgUUID("org-uuid"))
+
+	events, err := am.Audit().List(context.Background(), AuditFilter{
+		Start: time.Date(2026, 1, 30, 23, 0, 0, 0, time.UTC),
+		// The end is read as the whole of its day
+		End: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("events = %v, want %v", ids, want)
+	}
+	// An empty page with a cursor is followed, and each day starts afresh
+	want := []string{"2026-01-30/", "2026-01-30/c1", "2026-01-30/c2", "2026-01-31/", "2026-02-01/"}
+	if !reflect.DeepEqual(srv.requests, want) {
+		t.Errorf("requests = %v, want %v", srv.requests, want)
+	}
+}
+
+func TestAuditEventsRange(t *testing.T) {
+	srv := &auditPages{}
+	am := newTestClient(t, srv.ServeHTTP, WithOrgUUID("org-uuid"))
+	day := time.Date(2026, 1, 30, 12, 0, 0, 0, time.UTC)
+
+	// An end before the start fetches nothing
+	it := am.Audit().Events(context.Background(), AuditFilter{Start: day, End: day.AddDate(0, 0, -1)})
+	if it.Next() || it.Err() != nil || len(srv.requests) != 0 {
+		t.Errorf("reversed range made %d requests, err %v", len(srv.requests), it.Err())
+	}
+
+	// No end means the start's day only
+	it = am.Audit().Events(context.Background(), AuditFilter{Start: day})
+	for it.Next() {
+	}
+	if want := []string{"2026-01-30/"}; !reflect.DeepEqual(srv.requests, want) {
+		t.Errorf("requests = %v, want %v", srv.requests, want)
+	}
+
+	// Once done, Next stays false without fetching again
+	if it.Next() || len(srv.requests) != 1 {
+		t.Errorf("finished iterator fetched again")
+	}
+}
+
+func TestAuditEventsErrors(t *testing.T) {
+	am := newTestClient(t, (&auditPages{}).ServeHTTP)
+	it := am.Audit().Events(context.Background(), AuditFilter{})
+	if it.Next() || it.Err() == nil {
+		t.Errorf("missing organization UUID: err = %v", it.Err())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	am = newTestClient(t, (&auditPages{}).ServeHTTP, WithOrgUUID("org-uuid"))
+	it = am.Audit().Events(ctx, AuditFilter{})
+	if it.Next() || !errors.Is(it.Err(), context.Canceled) {
+		t.Errorf("cancelled context: err = %v", it.Err())
+	}
+}
+
+func TestNewAuditIterator(t *testing.T) {
+	errFault := errors.New("injected")
+	it := newAuditIterator(AuditEvents{{ID: "a"}, {ID: "b"}}, errFault)
+
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Event().ID)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("events = %v, want %v", ids, want)
+	}
+	if !errors.Is(it.Err(), errFault) {
+		t.Errorf("err = %v, want the injected error", it.Err())
+	}
+
+	it = newAuditIterator(nil, nil)
+	if it.Next() || it.Err() != nil {
+		t.Errorf("empty iterator: err = %v", it.Err())
+	}
+}
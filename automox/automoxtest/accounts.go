@@ -0,0 +1,88 @@
+package automoxtest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rk295/go-automox/automox"
+)
+
+var _ automox.AccountsService = (*Accounts)(nil)
+
+// Accounts is the fake accounts service
+type Accounts struct {
+	fake *Fake
+}
+
+// ListZones implements automox.AccountsService
+func (a *Accounts) ListZones(ctx context.Context) (automox.Zones, error) {
+	if err := a.fake.call(ctx, "Accounts.ListZones", nil); err != nil {
+		return nil, err
+	}
+
+	a.fake.mu.Lock()
+	defer a.fake.mu.Unlock()
+	return append(automox.Zones{}, a.fake.zones...), nil
+}
+
+// ListUsers implements automox.AccountsService
+func (a *Accounts) ListUsers(ctx context.Context) (automox.AccountUsers, error) {
+	if err := a.fake.call(ctx, "Accounts.ListUsers", nil); err != nil {
+		return nil, err
+	}
+
+	a.fake.mu.Lock()
+	defer a.fake.mu.Unlock()
+	users := automox.AccountUsers{}
+	for _, u := range a.fake.users {
+		u.Zones = append([]automox.ZoneAssignment(nil), u.Zones...)
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+// Invite implements automox.AccountsService. Invitations can be read back
+// with Fake.Invitations.
+func (a *Accounts) Invite(ctx context.Context, inv automox.AccountInvitation) (*automox.Invitation, error) {
+	if err := a.fake.call(ctx, "Accounts.Invite", nil, inv); err != nil {
+		return nil, err
+	}
+
+	a.fake.mu.Lock()
+	defer a.fake.mu.Unlock()
+	res := automox.Invitation{
+		ID:              fmt.Sprintf("00000000-0000-0000-0000-%012d", a.fake.nextID()),
+		Email:           inv.Email,
+		AccountRBACRole: inv.AccountRBACRole,
+		Zones:           append([]automox.ZoneAssignment(nil), inv.Zones...),
+		ExpiresAt:       automox.AutomoxTime(time.Now().AddDate(0, 0, 7)),
+	}
+	a.fake.invitations = append(a.fake.invitations, res)
+	return &res, nil
+}
+
+// RemoveFromZone implements automox.AccountsService
+func (a *Accounts) RemoveFromZone(ctx context.Context, zoneID, userID string) error {
+	if err := a.fake.call(ctx, "Accounts.RemoveFromZone", userID, zoneID, userID); err != nil {
+		return err
+	}
+
+	a.fake.mu.Lock()
+	defer a.fake.mu.Unlock()
+	u, ok := a.fake.users[userID]
+	if !ok {
+		return notFound("user", userID)
+	}
+	zones := []automox.ZoneAssignment{}
+	for _, z := range u.Zones {
+		if z.ZoneID != zoneID {
+			zones = append(zones, z)
+		}
+	}
+	u.Zones = zones
+	a.fake.users[userID] = u
+	return nil
+}
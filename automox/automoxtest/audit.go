@@ -0,0 +1,65 @@
+package automoxtest
+
+import (
+	"context"
+	"time"
+
+	"github.com/rk295/go-automox/automox"
+	"github.com/rk295/go-automox/automox/internal/shared"
+)
+
+var _ automox.AuditService = (*Audit)(nil)
+
+// Audit is the fake audit service
+type Audit struct {
+	fake *Fake
+}
+
+// List implements automox.AuditService
+func (a *Audit) List(ctx context.Context, f automox.AuditFilter) (automox.AuditEvents, error) {
+	if err := a.fake.call(ctx, "Audit.List", nil, f); err != nil {
+		return nil, err
+	}
+	return a.fake.auditEvents(f), nil
+}
+
+// Events implements automox.AuditService. An injected fault is returned by
+// the iterator's Err.
+func (a *Audit) Events(ctx context.Context, f automox.AuditFilter) *automox.AuditIterator {
+	newIterator := shared.NewAuditIterator.(func(automox.AuditEvents, error) *automox.AuditIterator)
+	if err := a.fake.call(ctx, "Audit.Events", nil, f); err != nil {
+		return newIterator(nil, err)
+	}
+	return newIterator(a.fake.auditEvents(f), nil)
+}
+
+// auditEvents returns the events matching the filter, treating its days as
+// the client does
+func (f *Fake) auditEvents(flt automox.AuditFilter) automox.AuditEvents {
+	start := flt.Start
+	if start.IsZero() {
+		start = time.Now()
+	}
+	end := flt.End
+	if end.IsZero() {
+		end = start
+	}
+	from, to := shared.TruncateDay(start), shared.TruncateDay(end).AddDate(0, 0, 1)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events := automox.AuditEvents{}
+	for _, e := range f.events {
+		if e.Time.Before(from) || !e.Time.Before(to) {
+			continue
+		}
+		if flt.Actor != "" && e.Actor.Email != flt.Actor {
+			continue
+		}
+		if flt.Action != "" && e.Action != flt.Action {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events
+}
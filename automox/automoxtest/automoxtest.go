@@ -0,0 +1,341 @@
+// Package automoxtest provides an in-memory fake of the Automox API client
+// for testing code which uses it.
+//
+//	fake := automoxtest.New(automoxtest.Fixtures{
+//		Servers: automox.Servers{{ID: 1, Name: "web-1"}},
+//	})
+//	fake.Inject(automoxtest.Fault{Method: "Servers.Get", ID: 1, Err: errors.New("boom")})
+//
+// Fake has the same service accessors as *automox.Client, so code written
+// against an interface such as
+//
+//	type API interface {
+//		Servers() automox.ServersService
+//		Policies() automox.PoliciesService
+//	}
+//
+// accepts either. Writes change the fake's state, so a device tagged through
+// the fake carries the tag when it is next read.
+package automoxtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rk295/go-automox/automox"
+)
+
+// Fixtures seed the state of a Fake. They can be loaded from JSON, as the
+// fakeautomox command does.
+type Fixtures struct {
+	Servers automox.Servers `json:"servers"`
+	// Packages and CommandQueues are keyed by server ID
+	Packages      map[int64]automox.Packages     `json:"packages"`
+	CommandQueues map[int64]automox.CommandQueue `json:"command_queues"`
+	// Inventory is keyed by device UUID
+	Inventory    map[string]automox.Inventory `json:"inventory"`
+	ServerGroups automox.ServerGroups         `json:"server_groups"`
+	Policies     automox.Policies             `json:"policies"`
+	ActionSets   automox.ActionSets           `json:"action_sets"`
+	// Solutions are keyed by action set ID
+	Solutions   map[int64]automox.ActionSetSolutions `json:"solutions"`
+	AuditEvents automox.AuditEvents                  `json:"audit_events"`
+	Zones       automox.Zones                        `json:"zones"`
+	Users       automox.AccountUsers                 `json:"users"`
+}
+
+// Call is a recorded call to one of the fake's services
+type Call struct {
+	// Method is the service and method called, such as "Servers.Get"
+	Method string
+	// Args are the arguments after the context
+	Args []interface{}
+}
+
+// Fault is an error or delay injected into matching calls
+type Fault struct {
+	// Method is the service and method to match, such as "Servers.Get".
+	// Empty matches every method.
+	Method string
+	// ID is the ID the call is for, compared as formatted by fmt.Sprint so
+	// 42 matches int64(42). It is the server, policy or action set ID, the
+	// device UUID for Servers.Inventory and the user ID for
+	// Accounts.RemoveFromZone. Nil matches every call.
+	ID interface{}
+	// Err is returned by matching calls, after any Delay
+	Err error
+	// Delay holds matching calls up, or until their context is done
+	Delay time.Duration
+	// Times limits the fault to the first Times matching calls. Zero
+	// applies it to every matching call.
+	Times int
+}
+
+// fault is an injected fault and how often it has applied
+type fault struct {
+	Fault
+	used int
+}
+
+// Fake is an in-memory stand-in for the Automox API. It is safe for
+// concurrent use.
+type Fake struct {
+	mu sync.Mutex
+
+	servers     map[int64]automox.ServerDetails
+	packages    map[int64]automox.Packages
+	queues      map[int64]automox.CommandQueue
+	inventory   map[string]automox.Inventory
+	groups      automox.ServerGroups
+	policies    map[int64]automox.Policy
+	policyFiles map[int64]map[string][]byte
+	actionSets  map[int64]automox.ActionSet
+	uploads     map[int64][]byte
+	solutions   map[int64]automox.ActionSetSolutions
+	executed    map[int64][]automox.RemediationAction
+	events      automox.AuditEvents
+	zones       automox.Zones
+	users       map[string]automox.AccountUser
+	invitations []automox.Invitation
+
+	calls  []Call
+	faults []*fault
+	lastID int64
+}
+
+// New returns a fake seeded with the given fixtures
+func New(fx Fixtures) *Fake {
+	f := &Fake{
+		servers:     map[int64]automox.ServerDetails{},
+		packages:    map[int64]automox.Packages{},
+		queues:      map[int64]automox.CommandQueue{},
+		inventory:   map[string]automox.Inventory{},
+		policies:    map[int64]automox.Policy{},
+		policyFiles: map[int64]map[string][]byte{},
+		actionSets:  map[int64]automox.ActionSet{},
+		uploads:     map[int64][]byte{},
+		solutions:   map[int64]automox.ActionSetSolutions{},
+		executed:    map[int64][]automox.RemediationAction{},
+		users:       map[string]automox.AccountUser{},
+	}
+	f.Seed(fx)
+	return f
+}
+
+// Seed adds the fixtures to the fake's state, replacing any entries with
+// the same IDs
+func (f *Fake) Seed(fx Fixtures) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, s := range fx.Servers {
+		s.Tags = append([]string(nil), s.Tags...)
+		f.servers[int64(s.ID)] = s
+		f.seenID(int64(s.ID))
+	}
+	for id, p := range fx.Packages {
+		f.packages[id] = p
+	}
+	for id, q := range fx.CommandQueues {
+		f.queues[id] = q
+	}
+	for uuid, inv := range fx.Inventory {
+		f.inventory[uuid] = inv
+	}
+	f.groups = append(f.groups, fx.ServerGroups...)
+	for _, p := range fx.Policies {
+		f.policies[int64(p.ID)] = p
+		f.seenID(int64(p.ID))
+	}
+	for _, a := range fx.ActionSets {
+		f.actionSets[a.ID] = a
+		f.seenID(a.ID)
+	}
+	for id, s := range fx.Solutions {
+		f.solutions[id] = s
+	}
+	f.events = append(f.events, fx.AuditEvents...)
+	f.zones = append(f.zones, fx.Zones...)
+	for _, u := range fx.Users {
+		f.users[u.ID] = u
+	}
+}
+
+// seenID keeps IDs handed out by the fake clear of seeded ones. f.mu must
+// be held.
+func (f *Fake) seenID(id int64) {
+	if id > f.lastID {
+		f.lastID = id
+	}
+}
+
+// nextID returns an unused ID. f.mu must be held.
+func (f *Fake) nextID() int64 {
+	f.lastID++
+	return f.lastID
+}
+
+// Inject adds a fault. Faults are checked in the order injected and the
+// first match applies.
+func (f *Fake) Inject(flt Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = append(f.faults, &fault{Fault: flt})
+}
+
+// ClearFaults removes every injected fault
+func (f *Fake) ClearFaults() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = nil
+}
+
+// Calls returns every call made so far, in order
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsTo returns the calls made to a method, such as "Servers.Get"
+func (f *Fake) CallsTo(method string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []Call
+	for _, c := range f.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// ResetCalls forgets the calls recorded so far
+func (f *Fake) ResetCalls() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+// Executed returns the remediation actions executed for an action set
+func (f *Fake) Executed(actionSetID int64) []automox.RemediationAction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]automox.RemediationAction(nil), f.executed[actionSetID]...)
+}
+
+// Uploaded returns the CSV uploaded to create an action set
+func (f *Fake) Uploaded(actionSetID int64) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, ok := f.uploads[actionSetID]
+	return b, ok
+}
+
+// PolicyFile returns a file uploaded to a policy
+func (f *Fake) PolicyFile(policyID int64, filename string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, ok := f.policyFiles[policyID][filename]
+	return b, ok
+}
+
+// Invitations returns every invitation sent
+func (f *Fake) Invitations() []automox.Invitation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]automox.Invitation(nil), f.invitations...)
+}
+
+// Servers returns the fake servers service
+func (f *Fake) Servers() automox.ServersService {
+	return &Servers{fake: f}
+}
+
+// VulnSync returns the fake Vulnerability Sync service
+func (f *Fake) VulnSync() automox.VulnSyncService {
+	return &VulnSync{fake: f}
+}
+
+// Audit returns the fake audit service
+func (f *Fake) Audit() automox.AuditService {
+	return &Audit{fake: f}
+}
+
+// Accounts returns the fake accounts service
+func (f *Fake) Accounts() automox.AccountsService {
+	return &Accounts{fake: f}
+}
+
+// Policies returns the fake policies service
+func (f *Fake) Policies() automox.PoliciesService {
+	return &Policies{fake: f}
+}
+
+// ServerGroups returns the fake server groups service
+func (f *Fake) ServerGroups() automox.ServerGroupsService {
+	return &ServerGroups{fake: f}
+}
+
+// call records a call and applies the first matching fault, returning the
+// error the call should fail with
+func (f *Fake) call(ctx context.Context, method string, id interface{}, args ...interface{}) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, Call{Method: method, Args: args})
+	var match *Fault
+	for _, flt := range f.faults {
+		if flt.matches(method, id) {
+			flt.used++
+			match = &flt.Fault
+			break
+		}
+	}
+	f.mu.Unlock()
+
+	if match == nil {
+		return ctx.Err()
+	}
+	if match.Delay > 0 {
+		t := time.NewTimer(match.Delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+	return match.Err
+}
+
+// matches reports whether the fault applies to a call
+func (flt *fault) matches(method string, id interface{}) bool {
+	if flt.Times > 0 && flt.used >= flt.Times {
+		return false
+	}
+	if flt.Method != "" && flt.Method != method {
+		return false
+	}
+	return flt.ID == nil || (id != nil && fmt.Sprint(flt.ID) == fmt.Sprint(id))
+}
+
+// notFound is the error returned for unknown IDs, as the API would
+func notFound(what string, id interface{}) error {
+	return &automox.ErrorResponse{
+		StatusCode: http.StatusNotFound,
+		Message:    fmt.Sprintf("%s %v not found", what, id),
+	}
+}
+
+// sortedIDs returns the keys of m in ascending order
+func sortedIDs[V any](m map[int64]V) []int64 {
+	ids := make([]int64, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,103 @@
+package automoxtest
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/rk295/go-automox/automox"
+)
+
+var (
+	_ automox.PoliciesService     = (*Policies)(nil)
+	_ automox.ServerGroupsService = (*ServerGroups)(nil)
+)
+
+// Policies is the fake policies service
+type Policies struct {
+	fake *Fake
+}
+
+// List implements automox.PoliciesService
+func (p *Policies) List(ctx context.Context) (automox.Policies, error) {
+	if err := p.fake.call(ctx, "Policies.List", nil); err != nil {
+		return nil, err
+	}
+
+	p.fake.mu.Lock()
+	defer p.fake.mu.Unlock()
+	policies := automox.Policies{}
+	for _, id := range sortedIDs(p.fake.policies) {
+		policies = append(policies, p.fake.policies[id])
+	}
+	return policies, nil
+}
+
+// Get implements automox.PoliciesService
+func (p *Policies) Get(ctx context.Context, id int64) (*automox.Policy, error) {
+	if err := p.fake.call(ctx, "Policies.Get", id, id); err != nil {
+		return nil, err
+	}
+
+	p.fake.mu.Lock()
+	defer p.fake.mu.Unlock()
+	policy, ok := p.fake.policies[id]
+	if !ok {
+		return nil, notFound("policy", id)
+	}
+	return &policy, nil
+}
+
+// Create implements automox.PoliciesService, giving the policy a new ID
+func (p *Policies) Create(ctx context.Context, policy automox.Policy) (*automox.Policy, error) {
+	if err := p.fake.call(ctx, "Policies.Create", nil, policy); err != nil {
+		return nil, err
+	}
+
+	p.fake.mu.Lock()
+	defer p.fake.mu.Unlock()
+	policy.ID = int(p.fake.nextID())
+	policy.ServerCount = 0
+	policy.CreateTime = automox.AutomoxTime(time.Now())
+	p.fake.policies[int64(policy.ID)] = policy
+	return &policy, nil
+}
+
+// UploadFile implements automox.PoliciesService. The file can be read back
+// with Fake.PolicyFile.
+func (p *Policies) UploadFile(ctx context.Context, id int64, filename string, r io.Reader) error {
+	if err := p.fake.call(ctx, "Policies.UploadFile", id, id, filename); err != nil {
+		return err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	p.fake.mu.Lock()
+	defer p.fake.mu.Unlock()
+	if _, ok := p.fake.policies[id]; !ok {
+		return notFound("policy", id)
+	}
+	if p.fake.policyFiles[id] == nil {
+		p.fake.policyFiles[id] = map[string][]byte{}
+	}
+	p.fake.policyFiles[id][filename] = b
+	return nil
+}
+
+// ServerGroups is the fake server groups service
+type ServerGroups struct {
+	fake *Fake
+}
+
+// List implements automox.ServerGroupsService
+func (g *ServerGroups) List(ctx context.Context) (automox.ServerGroups, error) {
+	if err := g.fake.call(ctx, "ServerGroups.List", nil); err != nil {
+		return nil, err
+	}
+
+	g.fake.mu.Lock()
+	defer g.fake.mu.Unlock()
+	return append(automox.ServerGroups{}, g.fake.groups...), nil
+}
@@ -0,0 +1,257 @@
+package automoxtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RecorderMode is whether a Recorder records or replays
+type RecorderMode int
+
+const (
+	// ModeReplay answers requests from the cassette file, failing any the
+	// cassette has no answer for. Nothing is sent over the network.
+	ModeReplay RecorderMode = iota
+	// ModeRecord sends requests on and records them, scrubbed, for Save to
+	// write to the cassette file
+	ModeRecord
+)
+
+// ErrNoInteraction is returned when replaying a request the cassette has
+// no recording of
+var ErrNoInteraction = errors.New("automoxtest: no recorded interaction")
+
+// Cassette is the set of interactions held in a cassette file
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a scrubbed request. URL holds just the path and query.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	RecordedBody
+}
+
+// RecordedResponse is a scrubbed response
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	RecordedBody
+}
+
+// RecordedBody holds a JSON body as JSON, to keep cassettes readable, and
+// any other body as text
+type RecordedBody struct {
+	JSON json.RawMessage `json:"body,omitempty"`
+	Text string          `json:"text,omitempty"`
+}
+
+// bytes returns the body as it was sent
+func (b RecordedBody) bytes() []byte {
+	if len(b.JSON) > 0 {
+		return b.JSON
+	}
+	return []byte(b.Text)
+}
+
+// Recorder is an http.RoundTripper which records the client's traffic to a
+// cassette file or replays it from one, in the style of VCR:
+//
+//	mode := automoxtest.ModeReplay
+//	if os.Getenv("AUTOMOX_RECORD") == "1" {
+//		mode = automoxtest.ModeRecord
+//	}
+//	rec, err := automoxtest.NewRecorder("testdata/servers.json", mode, nil)
+//	defer rec.Save()
+//	api, err := automox.New(ctx, token, rec.Client())
+//
+// Recordings are scrubbed before they are kept. Credentials are removed from
+// headers and the query, and the token is removed wherever it appears. In
+// JSON bodies, IP and MAC addresses, serial numbers and hostnames are found
+// by field name, such as ip_addrs, serial_number or the name of a device,
+// and replaced with placeholders. A value is given the same placeholder
+// everywhere it is seen in a recording, so a hostname listed by one call
+// still matches the same device fetched by another. Other bodies, such as
+// CSV uploads, only have the token removed; AddSecret scrubs anything else.
+//
+// Replayed requests are matched on method, path and query. Repeated
+// requests get the recorded responses in order, and then the last again.
+type Recorder struct {
+	path string
+	mode RecorderMode
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+	scrub    *scrubber
+}
+
+// NewRecorder returns a recorder for the cassette file at path. When
+// recording, requests are sent with next, or http.DefaultTransport if nil.
+// When replaying, the cassette file is read now.
+func NewRecorder(path string, mode RecorderMode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{path: path, mode: mode, next: next, scrub: newScrubber()}
+	if mode != ModeReplay {
+		return r, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &r.cassette); err != nil {
+		return nil, fmt.Errorf("reading cassette %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// AddSecret has value scrubbed from recordings wherever it appears
+func (r *Recorder) AddSecret(value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scrub.secret(value)
+}
+
+// Client returns an HTTP client sending requests through the recorder
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Save writes the recorded interactions to the cassette file. It does
+// nothing when replaying.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(b, '\n'), 0o644)
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == ModeReplay {
+		return r.replay(req)
+	}
+	return r.record(req)
+}
+
+// record sends the request on and keeps a scrubbed copy of the exchange
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+
+	res, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		r.scrub.secret(strings.TrimPrefix(auth, "Bearer "))
+	}
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: RecordedRequest{
+			Method:       req.Method,
+			URL:          r.scrub.url(req.URL),
+			Header:       r.scrub.header(req.Header),
+			RecordedBody: r.scrub.body(reqBody),
+		},
+		Response: RecordedResponse{
+			StatusCode:   res.StatusCode,
+			Header:       r.scrub.header(res.Header),
+			RecordedBody: r.scrub.body(resBody),
+		},
+	})
+	return res, nil
+}
+
+// replay answers the request from the cassette
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	want := r.scrub.url(req.URL)
+	found := -1
+	for i, in := range r.cassette.Interactions {
+		if in.Request.Method != req.Method || in.Request.URL != want {
+			continue
+		}
+		found = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if found < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, want)
+	}
+	r.used[found] = true
+
+	rec := r.cassette.Interactions[found].Response
+	body := rec.bytes()
+	header := rec.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.StatusCode, http.StatusText(rec.StatusCode)),
+		StatusCode:    rec.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
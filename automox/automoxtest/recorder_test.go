@@ -0,0 +1,129 @@
+package automoxtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rk295/go-automox/automox"
+)
+
+// recordedCalls makes the same calls against whatever am talks to,
+// returning the custom name of server 1 before and after renaming it, which
+// are scrubbed hostnames once recorded
+func recordedCalls(t *testing.T, am *automox.Client) (before, after string) {
+	t.Helper()
+	ctx := context.Background()
+	servers := am.Servers()
+
+	list, err := servers.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("listed %d servers, want 2", len(list))
+	}
+	s, err := servers.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if _, err := am.Do(ctx, http.MethodPut, "/api/servers/1", nil, map[string]interface{}{
+		"server_group_id": 7,
+		"custom_name":     "Finance 2",
+		"tags":            []string{"org-secret"},
+	}, nil); err != nil {
+		t.Fatalf("PUT: %v", err)
+	}
+	renamed, err := servers.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get after PUT: %v", err)
+	}
+	return s.CustomName, renamed.CustomName
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	ctx := context.Background()
+	cassette := filepath.Join(t.TempDir(), "testdata", "servers.json")
+
+	f := New(Fixtures{Servers: automox.Servers{
+		{ID: 1, Name: "fin-laptop-07", CustomName: "Finance", IPAddrs: []string{"81.2.69.142"}, SerialNumber: "5CG1234XYZ"},
+		{ID: 2, Name: "web-01", IPAddrsPrivate: []string{"10.20.30.40"}},
+	}})
+	srv := NewServer(f, ServerConfig{Token: "live-token"})
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := NewRecorder(cassette, ModeRecord, srv.Client().Transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.AddSecret("org-secret")
+	am, err := automox.New(ctx, "live-token", rec.Client(), automox.WithBaseURL(u))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordedCalls(t, am)
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"live-token", "org-secret", "fin-laptop-07", "Finance", "81.2.69.142", "10.20.30.40", "5CG1234XYZ"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+	var c Cassette
+	if err := json.Unmarshal(b, &c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Interactions) != 4 {
+		t.Fatalf("%d interactions recorded, want 4", len(c.Interactions))
+	}
+	var names []string
+	for _, in := range c.Interactions {
+		if in.Request.Method == http.MethodGet && in.Request.URL == "/api/servers/1" {
+			var s automox.ServerDetails
+			if err := json.Unmarshal(in.Response.JSON, &s); err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, s.CustomName)
+		}
+	}
+	if len(names) != 2 {
+		t.Fatalf("recorded %d gets of server 1, want 2", len(names))
+	}
+	wantBefore, wantAfter := names[0], names[1]
+
+	// Replaying needs neither the server nor the token
+	srv.Close()
+	replay, err := NewRecorder(cassette, ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	am, err = automox.New(ctx, "other-token", replay.Client(), automox.WithBaseURL(u))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, after := recordedCalls(t, am)
+	if before != wantBefore || after != wantAfter || before == after {
+		t.Errorf("replayed names %q and %q, recorded %q and %q", before, after, wantBefore, wantAfter)
+	}
+
+	// A request the cassette has no answer for fails
+	if _, err := am.Servers().Get(ctx, 2); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("unrecorded request: error = %v, want ErrNoInteraction", err)
+	}
+}
@@ -0,0 +1,88 @@
+package automoxtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/rk295/go-automox/automox"
+)
+
+// TB is the part of testing.TB the schema helpers use
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// routeTypes are the types responses to each route decode into
+var routeTypes = map[string]interface{}{
+	"/api/servers":                                             automox.Servers{},
+	"/api/servers/{id}":                                        automox.ServerDetails{},
+	"/api/servers/{id}/packages":                               automox.Packages{},
+	"/api/servers/{id}/queues":                                 automox.CommandQueue{},
+	"/api/servergroups":                                        automox.ServerGroups{},
+	"/api/policies":                                            automox.Policies{},
+	"/api/policies/{id}":                                       automox.Policy{},
+	"/api/orgs/{id}/remediations/action-sets":                  automox.ActionSets{},
+	"/api/orgs/{id}/remediations/action-sets/{id}":             automox.ActionSet{},
+	"/api/device-details/orgs/{uuid}/devices/{uuid}/inventory": automox.Inventory{},
+}
+
+// CheckSchema fails the test for every field of a JSON payload which v, a
+// pointer to the type the payload decodes into, has no field for or cannot
+// hold. Run over a live payload it catches drift in the Automox API:
+//
+//	automoxtest.CheckSchema(t, payload, &automox.Servers{})
+func CheckSchema(t TB, data []byte, v interface{}) {
+	t.Helper()
+	checkSchema(t, fmt.Sprintf("%T", v), data, v)
+}
+
+// checkSchema reports each difference between a payload and v, labelled
+// with where the payload came from
+func checkSchema(t TB, label string, data []byte, v interface{}) {
+	t.Helper()
+	warnings, err := automox.DiffSchema(data, v)
+	if err != nil {
+		t.Errorf("%s: decoding payload: %v", label, err)
+		return
+	}
+	for _, w := range warnings {
+		t.Errorf("%s: %s", label, w)
+	}
+}
+
+// CheckCassette runs CheckSchema over the successful GET responses in a
+// cassette file for the routes whose types it knows, such as /api/servers
+// and /api/servers/{id}/packages. Recording a cassette against the live API
+// and checking it makes a nightly drift check.
+func CheckCassette(t TB, path string) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("reading cassette: %v", err)
+		return
+	}
+	var c Cassette
+	if err := json.Unmarshal(b, &c); err != nil {
+		t.Errorf("reading cassette %s: %v", path, err)
+		return
+	}
+
+	for _, in := range c.Interactions {
+		res := in.Response
+		if in.Request.Method != http.MethodGet || res.StatusCode < 200 || res.StatusCode > 299 || len(res.JSON) == 0 {
+			continue
+		}
+		p, _, _ := strings.Cut(in.Request.URL, "?")
+		typ, ok := routeTypes[automox.RouteTemplate(p)]
+		if !ok {
+			continue
+		}
+		v := reflect.New(reflect.TypeOf(typ)).Interface()
+		checkSchema(t, in.Request.Method+" "+in.Request.URL, res.JSON, v)
+	}
+}
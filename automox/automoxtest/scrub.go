@@ -0,0 +1,256 @@
+package automoxtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/rk295/go-automox/automox/internal/shared"
+)
+
+// Kinds of scrubbed value, each with its own placeholders
+const (
+	kindSecret   = "secret"
+	kindIPv4     = "ipv4"
+	kindIPv6     = "ipv6"
+	kindMAC      = "mac"
+	kindSerial   = "serial"
+	kindHostname = "hostname"
+)
+
+// scrubber replaces sensitive values in recordings with placeholders,
+// remembering which placeholder each value was given
+type scrubber struct {
+	seen    map[string]string
+	count   map[string]int
+	secrets []string
+}
+
+func newScrubber() *scrubber {
+	return &scrubber{seen: map[string]string{}, count: map[string]int{}}
+}
+
+// secret scrubs value wherever it appears
+func (s *scrubber) secret(value string) {
+	if _, ok := s.seen[value]; value != "" && !ok {
+		s.replace(kindSecret, value)
+		s.secrets = append(s.secrets, value)
+	}
+}
+
+// replace returns the placeholder for value, giving it one if it has none
+func (s *scrubber) replace(kind, value string) string {
+	if p, ok := s.seen[value]; ok {
+		return p
+	}
+
+	s.count[kind]++
+	n := s.count[kind]
+	var p string
+	switch kind {
+	case kindSecret:
+		p = "REDACTED"
+	case kindIPv4:
+		p = ipv4Placeholder(n)
+	case kindIPv6:
+		p = fmt.Sprintf("2001:db8::%x", n)
+	case kindMAC:
+		p = macPlaceholder(n)
+	case kindSerial:
+		p = fmt.Sprintf("SERIAL%06d", n)
+	case kindHostname:
+		p = fmt.Sprintf("host-%d", n)
+	}
+	s.seen[value] = p
+	return p
+}
+
+// testNets are the IPv4 documentation ranges, each with 254 usable hosts
+var testNets = []string{"192.0.2", "198.51.100", "203.0.113"}
+
+// ipv4Placeholder returns the nth IPv4 placeholder, taken from the
+// documentation ranges and then from the reserved 240.0.0.0/4 range, so
+// that no two values share one
+func ipv4Placeholder(n int) string {
+	i := n - 1
+	if i < len(testNets)*254 {
+		return fmt.Sprintf("%s.%d", testNets[i/254], i%254+1)
+	}
+	i -= len(testNets) * 254
+	return fmt.Sprintf("%d.%d.%d.%d", 240|(i>>24)&0x0f, (i>>16)&0xff, (i>>8)&0xff, i&0xff)
+}
+
+// macPlaceholder returns the nth MAC address placeholder, taken from the
+// documentation range and then from locally administered addresses, so
+// that no two values share one
+func macPlaceholder(n int) string {
+	i := uint64(n - 1)
+	if i < 256 {
+		return fmt.Sprintf("00:00:5e:00:53:%02x", i)
+	}
+	i -= 256
+	return fmt.Sprintf("02:%02x:%02x:%02x:%02x:%02x", (i>>32)&0xff, (i>>24)&0xff, (i>>16)&0xff, (i>>8)&0xff, i&0xff)
+}
+
+// known returns the placeholder of a value already scrubbed elsewhere
+func (s *scrubber) known(value string) (string, bool) {
+	p, ok := s.seen[value]
+	return p, ok
+}
+
+// text replaces every secret found in free text, such as a CSV upload
+func (s *scrubber) text(t string) string {
+	for _, value := range s.secrets {
+		t = strings.ReplaceAll(t, value, "REDACTED")
+	}
+	return t
+}
+
+// header returns a copy of h without credentials
+func (s *scrubber) header(h http.Header) http.Header {
+	out := h.Clone()
+	for k, vs := range out {
+		if shared.IsSensitiveHeader(k) {
+			delete(out, k)
+			continue
+		}
+		for i, v := range vs {
+			out[k][i] = s.text(v)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// url returns the path and query of u with credentials removed and any
+// segment or value already scrubbed elsewhere replaced
+func (s *scrubber) url(u *url.URL) string {
+	segments := strings.Split(u.Path, "/")
+	for i, seg := range segments {
+		if p, ok := s.known(seg); ok {
+			segments[i] = p
+		}
+	}
+	out := strings.Join(segments, "/")
+
+	q := u.Query()
+	for k, vs := range q {
+		for i, v := range vs {
+			if shared.IsSensitiveParam(k) {
+				vs[i] = "REDACTED"
+			} else if p, ok := s.known(v); ok {
+				vs[i] = p
+			}
+		}
+	}
+	if len(q) > 0 {
+		out += "?" + q.Encode()
+	}
+	return out
+}
+
+// body scrubs a body, keeping JSON bodies as JSON
+func (s *scrubber) body(b []byte) RecordedBody {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return RecordedBody{}
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil || d.More() {
+		return RecordedBody{Text: s.text(string(b))}
+	}
+
+	// Find the sensitive fields first, so every other mention of their
+	// values can then be replaced
+	v = s.fields(v, "", false)
+	v = s.values(v)
+	out, err := json.Marshal(v)
+	if err != nil {
+		return RecordedBody{Text: s.text(string(b))}
+	}
+	return RecordedBody{JSON: out}
+}
+
+// fields replaces the values of sensitive fields. key is the field v is
+// held in and device whether v is inside a device record, whose name is
+// its hostname.
+func (s *scrubber) fields(v interface{}, key string, device bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		_, isDevice := v["os_family"]
+		// Visit fields in order so placeholders are numbered the same way
+		// each time a recording is made
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v[k] = s.fields(v[k], k, isDevice)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = s.fields(child, key, device)
+		}
+		return v
+	case string:
+		if kind := fieldKind(key, device); kind != "" && v != "" {
+			if kind == kindIPv4 && strings.Contains(v, ":") {
+				kind = kindIPv6
+			}
+			return s.replace(kind, v)
+		}
+		return v
+	}
+	return v
+}
+
+// values replaces every string equal to a value already scrubbed
+func (s *scrubber) values(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			v[k] = s.values(child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = s.values(child)
+		}
+		return v
+	case string:
+		if p, ok := s.known(v); ok {
+			return p
+		}
+		return s.text(v)
+	}
+	return v
+}
+
+// fieldKind returns what kind of sensitive value a field holds, if any.
+// Keys are matched case insensitively, as device details use upper case
+// keys such as FQDNS and NICS[].MAC.
+func fieldKind(key string, device bool) string {
+	key = strings.ToLower(key)
+	switch {
+	case key == "hostname", key == "custom_name", key == "fqdn", key == "fqdns", device && key == "name":
+		return kindHostname
+	case strings.Contains(key, "serial"), key == "servicetag", key == "service_tag":
+		return kindSerial
+	case key == "mac", key == "mac_address", key == "mac_addresses":
+		return kindMAC
+	case key == "ip", key == "ips", strings.HasPrefix(key, "ip_"), strings.HasSuffix(key, "_ip"),
+		strings.HasPrefix(key, "ipv"), key == "default_gateway", key == "dns_servers":
+		return kindIPv4
+	}
+	return ""
+}
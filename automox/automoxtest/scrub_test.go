@@ -0,0 +1,115 @@
+package automoxtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/rk295/go-automox/automox"
+)
+
+func TestScrubServerDetails(t *testing.T) {
+	device := automox.ServerDetails{
+		ID:             1042,
+		Name:           "fin-laptop-07",
+		DisplayName:    "fin-laptop-07",
+		CustomName:     "Finance Laptop 7",
+		IPAddrs:        []string{"81.2.69.142"},
+		IPAddrsPrivate: []string{"10.20.30.40"},
+		OsFamily:       "Windows",
+		OsName:         "Windows 11 Pro",
+		SerialNumber:   "5CG1234XYZ",
+		Detail: automox.Detail{
+			Ips:        []string{"10.20.30.40", "fe80::1c2b:3d4e:5f60:7182"},
+			Fqdns:      []string{"fin-laptop-07.corp.example.net"},
+			Serial:     "5CG1234XYZ",
+			Servicetag: "5CG1234XYZ",
+			Model:      "HP EliteBook 840 G8",
+			Nics: []automox.Nics{
+				{Device: "Ethernet", Mac: "A4:BB:6D:12:34:56", Ips: []string{"10.20.30.40"}, Connected: true},
+				{Device: "Wi-Fi", Mac: "3C:9C:0F:AB:CD:EF", Ips: []string{"192.168.1.23", "fe80::aa:bb"}},
+			},
+		},
+	}
+	b, err := json.Marshal([]automox.ServerDetails{device})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := newScrubber().body(b)
+	if len(out.JSON) == 0 {
+		t.Fatalf("scrubbed body is not JSON: %q", out.Text)
+	}
+	got := string(out.JSON)
+	for _, leak := range []string{
+		"fin-laptop-07", "Finance Laptop 7", "corp.example.net", "5CG1234XYZ",
+		"81.2.69.142", "10.20.30.40", "192.168.1.23", "fe80::",
+		"A4:BB:6D:12:34:56", "3C:9C:0F:AB:CD:EF",
+	} {
+		if strings.Contains(got, leak) {
+			t.Errorf("scrubbed body still contains %q", leak)
+		}
+	}
+
+	var scrubbed []automox.ServerDetails
+	if err := json.Unmarshal(out.JSON, &scrubbed); err != nil {
+		t.Fatal(err)
+	}
+	d := scrubbed[0].Detail
+	// The same value is given the same placeholder everywhere it appears
+	if d.Nics[0].Ips[0] != d.Ips[0] || d.Ips[0] != scrubbed[0].IPAddrsPrivate[0] {
+		t.Errorf("10.20.30.40 scrubbed inconsistently: %v, %v, %v", d.Nics[0].Ips, d.Ips, scrubbed[0].IPAddrsPrivate)
+	}
+	if d.Serial != scrubbed[0].SerialNumber {
+		t.Errorf("serial scrubbed inconsistently: %q and %q", d.Serial, scrubbed[0].SerialNumber)
+	}
+	if d.Nics[0].Mac == d.Nics[1].Mac {
+		t.Errorf("different MACs share placeholder %q", d.Nics[0].Mac)
+	}
+	// Fields which identify nothing are kept
+	if d.Model != device.Detail.Model || d.Nics[1].Device != "Wi-Fi" {
+		t.Errorf("model %q and NIC %q were altered", d.Model, d.Nics[1].Device)
+	}
+}
+
+func TestScrubPlaceholdersUnique(t *testing.T) {
+	s := newScrubber()
+	seen := map[string]bool{}
+	for i := 0; i < 2000; i++ {
+		for _, kind := range []string{kindIPv4, kindMAC} {
+			p := s.replace(kind, fmt.Sprintf("%s-%d", kind, i))
+			if seen[p] {
+				t.Fatalf("placeholder %q given to two values", p)
+			}
+			seen[p] = true
+
+			if kind == kindIPv4 && net.ParseIP(p).To4() == nil {
+				t.Errorf("placeholder %q is not an IPv4 address", p)
+			}
+			if _, err := net.ParseMAC(p); kind == kindMAC && err != nil {
+				t.Errorf("placeholder %q is not a MAC address", p)
+			}
+		}
+	}
+}
+
+func TestScrubCredentials(t *testing.T) {
+	s := newScrubber()
+	h := s.header(http.Header{
+		"Authorization": {"Bearer abc"},
+		"X-Api-Key":     {"abc"},
+		"Accept":        {"application/json"},
+	})
+	if len(h) != 1 || h.Get("Accept") != "application/json" {
+		t.Errorf("scrubbed headers = %v, want only Accept", h)
+	}
+
+	u, _ := url.Parse("/api/servers?o=1&API_KEY=abc")
+	if got := s.url(u); got != "/api/servers?API_KEY=REDACTED&o=1" {
+		t.Errorf("scrubbed URL = %s", got)
+	}
+}
@@ -0,0 +1,381 @@
+package automoxtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rk295/go-automox/automox"
+)
+
+// ServerConfig configures a fake API server
+type ServerConfig struct {
+	// Token is the API token requests must carry as a bearer token. Any
+	// other token is refused with a 401. Empty accepts any token.
+	Token string
+	// RateLimit is how many requests are allowed each second before further
+	// requests get a 429. Zero means no limit.
+	RateLimit int
+}
+
+// Handler serves a fake Automox API over HTTP from a Fake's state. Calls it
+// handles are recorded by the Fake and subject to its faults; a fault whose
+// Err is an *automox.ErrorResponse is answered with that status.
+//
+// It serves the server endpoints:
+//
+//	GET    /api/servers                      paged with limit and page
+//	GET    /api/servers/{id}
+//	PUT    /api/servers/{id}                 group, custom name, exception and tags
+//	DELETE /api/servers/{id}
+//	GET    /api/servers/{id}/packages
+//	GET    /api/servers/{id}/queues
+//	POST   /api/servers/{id}/queues          queues a command
+//	GET    /api/device-details/orgs/{org}/devices/{uuid}/inventory
+//
+// along with GET /api/servergroups, /api/policies and /api/policies/{id}.
+// Other methods on these paths are answered with a 405.
+type Handler struct {
+	fake *Fake
+
+	mu        sync.Mutex
+	cfg       ServerConfig
+	window    time.Time
+	used      int
+	requestID int64
+}
+
+// NewHandler returns a handler serving the fake's state
+func NewHandler(f *Fake, cfg ServerConfig) *Handler {
+	return &Handler{fake: f, cfg: cfg}
+}
+
+// SetToken changes the token requests must carry, as when it is rotated
+func (h *Handler) SetToken(token string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cfg.Token = token
+}
+
+// Server is a fake Automox API listening on a local port
+type Server struct {
+	*httptest.Server
+	*Handler
+}
+
+// NewServer starts a fake API server for the fake's state. Close it when
+// done.
+func NewServer(f *Fake, cfg ServerConfig) *Server {
+	h := NewHandler(f, cfg)
+	return &Server{Server: httptest.NewServer(h), Handler: h}
+}
+
+// APIClient returns an Automox client pointed at the server, using the
+// server's token
+func (s *Server) APIClient(ctx context.Context, opts ...automox.Option) (*automox.Client, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Handler.mu.Lock()
+	token := s.Handler.cfg.Token
+	s.Handler.mu.Unlock()
+	if token == "" {
+		token = "automoxtest"
+	}
+
+	opts = append([]automox.Option{automox.WithBaseURL(u)}, opts...)
+	return automox.New(ctx, token, s.Server.Client(), opts...)
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.admit(w, r) {
+		return
+	}
+
+	v, err := h.route(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if v == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// admit checks the request's token and the rate limit, answering requests
+// which fail either
+func (h *Handler) admit(w http.ResponseWriter, r *http.Request) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.requestID++
+	w.Header().Set("X-Request-Id", fmt.Sprintf("automoxtest-%d", h.requestID))
+
+	if h.cfg.Token != "" && r.Header.Get("Authorization") != "Bearer "+h.cfg.Token {
+		writeError(w, &automox.ErrorResponse{StatusCode: http.StatusUnauthorized, Message: "Unauthorized"})
+		return false
+	}
+
+	if h.cfg.RateLimit <= 0 {
+		return true
+	}
+	now := time.Now()
+	if now.Sub(h.window) >= time.Second {
+		h.window, h.used = now, 0
+	}
+	reset := h.window.Add(time.Second)
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	if h.used >= h.cfg.RateLimit {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("Retry-After", "1")
+		writeError(w, &automox.ErrorResponse{StatusCode: http.StatusTooManyRequests, Message: "Too Many Requests"})
+		return false
+	}
+	h.used++
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(h.cfg.RateLimit-h.used))
+	return true
+}
+
+// route dispatches a request, returning the value to encode as its body
+// or nil for no content
+func (h *Handler) route(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	servers := h.fake.Servers()
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case match(parts, "api", "servers"):
+		if r.Method == http.MethodGet {
+			return h.listServers(w, r)
+		}
+
+	case match(parts, "api", "servers", "*"):
+		id, err := pathID(parts[2])
+		if err != nil {
+			return nil, err
+		}
+		switch r.Method {
+		case http.MethodGet:
+			return servers.Get(ctx, id)
+		case http.MethodPut:
+			return nil, h.updateServer(r, id)
+		case http.MethodDelete:
+			return nil, h.fake.deleteServer(ctx, id)
+		}
+
+	case match(parts, "api", "servers", "*", "packages"):
+		id, err := pathID(parts[2])
+		if err != nil {
+			return nil, err
+		}
+		if r.Method == http.MethodGet {
+			return servers.GetPackages(ctx, id)
+		}
+
+	case match(parts, "api", "servers", "*", "queues"):
+		id, err := pathID(parts[2])
+		if err != nil {
+			return nil, err
+		}
+		switch r.Method {
+		case http.MethodGet:
+			return servers.GetCommandQueue(ctx, id)
+		case http.MethodPost:
+			return nil, h.queueCommand(r, id)
+		}
+
+	case match(parts, "api", "device-details", "orgs", "*", "devices", "*", "inventory"):
+		if r.Method == http.MethodGet {
+			return servers.Inventory(ctx, parts[5])
+		}
+
+	case match(parts, "api", "servergroups"):
+		if r.Method == http.MethodGet {
+			return h.fake.ServerGroups().List(ctx)
+		}
+
+	case match(parts, "api", "policies"):
+		if r.Method == http.MethodGet {
+			return h.fake.Policies().List(ctx)
+		}
+
+	case match(parts, "api", "policies", "*"):
+		id, err := pathID(parts[2])
+		if err != nil {
+			return nil, err
+		}
+		if r.Method == http.MethodGet {
+			return h.fake.Policies().Get(ctx, id)
+		}
+
+	default:
+		return nil, &automox.ErrorResponse{StatusCode: http.StatusNotFound, Message: "Not Found"}
+	}
+	// The path is served, but not for this method
+	return nil, &automox.ErrorResponse{StatusCode: http.StatusMethodNotAllowed, Message: "Method Not Allowed"}
+}
+
+// listServers serves a page of servers. Without a limit every server is
+// returned, as Automox does. X-Total-Count holds the number of servers.
+func (h *Handler) listServers(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	all, err := h.fake.Servers().List(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(all)))
+
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	page, _ := strconv.Atoi(q.Get("page"))
+	if limit <= 0 || page < 0 {
+		return all, nil
+	}
+
+	start := page * limit
+	if start > len(all) {
+		start = len(all)
+	}
+	end := start + limit
+	if end > len(all) {
+		end = len(all)
+	}
+	return all[start:end], nil
+}
+
+// updateRequest is the body of a server update
+type updateRequest struct {
+	ServerGroupID *int     `json:"server_group_id"`
+	CustomName    *string  `json:"custom_name"`
+	Exception     *bool    `json:"exception"`
+	Tags          []string `json:"tags"`
+}
+
+// updateServer applies a PUT to a server
+func (h *Handler) updateServer(r *http.Request, id int64) error {
+	var u updateRequest
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		return &automox.ErrorResponse{StatusCode: http.StatusBadRequest, Message: err.Error()}
+	}
+	if u.ServerGroupID == nil {
+		return &automox.ErrorResponse{
+			StatusCode: http.StatusBadRequest,
+			Fields:     map[string][]string{"server_group_id": {"is required"}},
+		}
+	}
+	if err := h.fake.call(r.Context(), "Servers.Update", id, id, u); err != nil {
+		return err
+	}
+
+	h.fake.mu.Lock()
+	defer h.fake.mu.Unlock()
+	s, ok := h.fake.servers[id]
+	if !ok {
+		return notFound("server", id)
+	}
+	s.ServerGroupID = *u.ServerGroupID
+	if u.CustomName != nil {
+		s.CustomName = *u.CustomName
+	}
+	if u.Exception != nil {
+		s.Exception = *u.Exception
+	}
+	s.Tags = append([]string(nil), u.Tags...)
+	h.fake.servers[id] = s
+	return nil
+}
+
+// queueCommand appends a command to a server's queue
+func (h *Handler) queueCommand(r *http.Request, id int64) error {
+	var cmd automox.CommandQueueItem
+	if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+		return &automox.ErrorResponse{StatusCode: http.StatusBadRequest, Message: err.Error()}
+	}
+	if err := h.fake.call(r.Context(), "Servers.QueueCommand", id, id, cmd); err != nil {
+		return err
+	}
+
+	h.fake.mu.Lock()
+	defer h.fake.mu.Unlock()
+	if _, ok := h.fake.servers[id]; !ok {
+		return notFound("server", id)
+	}
+	cmd.ExecTime = automox.AutomoxTime(time.Now())
+	h.fake.queues[id] = append(h.fake.queues[id], cmd)
+	return nil
+}
+
+// deleteServer removes a server and everything held for it
+func (f *Fake) deleteServer(ctx context.Context, id int64) error {
+	if err := f.call(ctx, "Servers.Delete", id, id); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.servers[id]; !ok {
+		return notFound("server", id)
+	}
+	delete(f.servers, id)
+	delete(f.packages, id)
+	delete(f.queues, id)
+	return nil
+}
+
+// match reports whether the path segments match the pattern, where *
+// matches any one segment
+func match(parts []string, pattern ...string) bool {
+	if len(parts) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pathID parses a numeric ID from a path segment
+func pathID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, &automox.ErrorResponse{StatusCode: http.StatusNotFound, Message: "Not Found"}
+	}
+	return id, nil
+}
+
+// writeError answers with an error in the shape Automox uses
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	body := map[string]interface{}{"errors": []string{err.Error()}}
+
+	var res *automox.ErrorResponse
+	if errors.As(err, &res) && res.StatusCode >= 400 {
+		status = res.StatusCode
+		body = map[string]interface{}{"message": res.Message, "errors": res.Errors}
+		if len(res.Fields) > 0 {
+			body["errors"] = res.Fields
+		}
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		status = http.StatusGatewayTimeout
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
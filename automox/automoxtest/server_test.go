@@ -0,0 +1,199 @@
+package automoxtest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rk295/go-automox/automox"
+)
+
+// send makes a raw request to the server with the given token, returning
+// the response and its body
+func send(t *testing.T, srv *Server, method, path, token, body string) (*http.Response, []byte) {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, srv.URL+path, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, b
+}
+
+func newTestServer(t *testing.T, cfg ServerConfig) (*Fake, *Server) {
+	t.Helper()
+	f := New(Fixtures{
+		Servers: automox.Servers{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}, {ID: 4, Name: "d"}, {ID: 5, Name: "e"}},
+	})
+	srv := NewServer(f, cfg)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func TestHandlerPaging(t *testing.T) {
+	_, srv := newTestServer(t, ServerConfig{})
+
+	for _, tt := range []struct {
+		query string
+		want  []int
+	}{
+		{"", []int{1, 2, 3, 4, 5}},
+		{"?limit=2&page=0", []int{1, 2}},
+		{"?limit=2&page=2", []int{5}},
+		{"?limit=2&page=3", []int{}},
+	} {
+		res, b := send(t, srv, http.MethodGet, "/api/servers"+tt.query, "", "")
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status %d", tt.query, res.StatusCode)
+		}
+		if got := res.Header.Get("X-Total-Count"); got != "5" {
+			t.Errorf("%s: X-Total-Count = %q, want 5", tt.query, got)
+		}
+		var servers automox.Servers
+		if err := json.Unmarshal(b, &servers); err != nil {
+			t.Fatal(err)
+		}
+		ids := []int{}
+		for _, s := range servers {
+			ids = append(ids, int(s.ID))
+		}
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("%s: IDs = %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+}
+
+func TestHandlerToken(t *testing.T) {
+	_, srv := newTestServer(t, ServerConfig{Token: "first"})
+
+	if res, _ := send(t, srv, http.MethodGet, "/api/servers", "", ""); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("no token: status %d, want 401", res.StatusCode)
+	}
+	if res, _ := send(t, srv, http.MethodGet, "/api/servers", "first", ""); res.StatusCode != http.StatusOK {
+		t.Errorf("right token: status %d, want 200", res.StatusCode)
+	}
+
+	srv.SetToken("second")
+	if res, _ := send(t, srv, http.MethodGet, "/api/servers", "first", ""); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("rotated out token: status %d, want 401", res.StatusCode)
+	}
+	if res, _ := send(t, srv, http.MethodGet, "/api/servers", "second", ""); res.StatusCode != http.StatusOK {
+		t.Errorf("new token: status %d, want 200", res.StatusCode)
+	}
+
+	// APIClient uses the current token
+	am, err := srv.APIClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := am.Servers().Get(context.Background(), 1); err != nil {
+		t.Errorf("APIClient: %v", err)
+	}
+}
+
+func TestHandlerRateLimit(t *testing.T) {
+	_, srv := newTestServer(t, ServerConfig{RateLimit: 2})
+
+	for i := 0; i < 2; i++ {
+		if res, _ := send(t, srv, http.MethodGet, "/api/servers/1", "", ""); res.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, res.StatusCode)
+		}
+	}
+	res, _ := send(t, srv, http.MethodGet, "/api/servers/1", "", "")
+	if res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("over the limit: status %d, want 429", res.StatusCode)
+	}
+	if res.Header.Get("Retry-After") != "1" || res.Header.Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("headers = %v", res.Header)
+	}
+}
+
+func TestHandlerWrites(t *testing.T) {
+	f, srv := newTestServer(t, ServerConfig{})
+	ctx := context.Background()
+
+	// PUT requires a server group
+	res, b := send(t, srv, http.MethodPut, "/api/servers/1", "", `{"custom_name":"web"}`)
+	if res.StatusCode != http.StatusBadRequest || !strings.Contains(string(b), "server_group_id") {
+		t.Errorf("PUT without server_group_id: status %d, body %s", res.StatusCode, b)
+	}
+	res, _ = send(t, srv, http.MethodPut, "/api/servers/1", "", `{"server_group_id":7,"custom_name":"web","tags":["prod"]}`)
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("PUT: status %d", res.StatusCode)
+	}
+	s, err := f.Servers().Get(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ServerGroupID != 7 || s.CustomName != "web" || len(s.Tags) != 1 || s.Tags[0] != "prod" {
+		t.Errorf("after PUT, server = %+v", s)
+	}
+	if res, _ := send(t, srv, http.MethodPut, "/api/servers/99", "", `{"server_group_id":7}`); res.StatusCode != http.StatusNotFound {
+		t.Errorf("PUT to a missing server: status %d, want 404", res.StatusCode)
+	}
+
+	// POST queues a command
+	res, _ = send(t, srv, http.MethodPost, "/api/servers/2/queues", "", `{"command_type_name":"InstallUpdate","args":"openssl"}`)
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("POST queue: status %d", res.StatusCode)
+	}
+	queue, err := f.Servers().GetCommandQueue(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*queue) != 1 || (*queue)[0].CommandTypeName != "InstallUpdate" || (*queue)[0].Args != "openssl" {
+		t.Errorf("queue = %+v", *queue)
+	}
+
+	// DELETE removes the server
+	if res, _ := send(t, srv, http.MethodDelete, "/api/servers/3", "", ""); res.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE: status %d", res.StatusCode)
+	}
+	if res, _ := send(t, srv, http.MethodGet, "/api/servers/3", "", ""); res.StatusCode != http.StatusNotFound {
+		t.Errorf("GET after DELETE: status %d, want 404", res.StatusCode)
+	}
+	if got := len(f.CallsTo("Servers.Delete")); got != 1 {
+		t.Errorf("Delete recorded %d times, want 1", got)
+	}
+}
+
+func TestHandlerRoutes(t *testing.T) {
+	_, srv := newTestServer(t, ServerConfig{})
+
+	for _, tt := range []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodPost, "/api/servers", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/api/servers/1/packages", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/api/servergroups", http.StatusMethodNotAllowed},
+		{http.MethodPut, "/api/policies/1", http.StatusMethodNotAllowed},
+		{http.MethodPatch, "/api/servers/1", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/servers/x", http.StatusNotFound},
+		{http.MethodGet, "/api/widgets", http.StatusNotFound},
+		{http.MethodPost, "/api/widgets", http.StatusNotFound},
+	} {
+		if res, _ := send(t, srv, tt.method, tt.path, "", "{}"); res.StatusCode != tt.status {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, res.StatusCode, tt.status)
+		}
+	}
+}
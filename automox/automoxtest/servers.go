@@ -0,0 +1,166 @@
+package automoxtest
+
+import (
+	"context"
+
+	"github.com/rk295/go-automox/automox"
+	"github.com/rk295/go-automox/automox/internal/shared"
+)
+
+var _ automox.ServersService = (*Servers)(nil)
+
+// Servers is the fake servers service
+type Servers struct {
+	fake *Fake
+}
+
+// List implements automox.ServersService
+func (s *Servers) List(ctx context.Context) (automox.Servers, error) {
+	if err := s.fake.call(ctx, "Servers.List", nil); err != nil {
+		return nil, err
+	}
+	return s.fake.listServers(), nil
+}
+
+// ListEach implements automox.ServersService
+func (s *Servers) ListEach(ctx context.Context, fn func(automox.ServerDetails) error) error {
+	if err := s.fake.call(ctx, "Servers.ListEach", nil); err != nil {
+		return err
+	}
+	for _, srv := range s.fake.listServers() {
+		if err := fn(srv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamList implements automox.ServersService
+func (s *Servers) StreamList(ctx context.Context) <-chan automox.ServerResult {
+	return shared.StreamServers.(func(context.Context, automox.ServersService) <-chan automox.ServerResult)(ctx, s)
+}
+
+// Get implements automox.ServersService
+func (s *Servers) Get(ctx context.Context, id int64) (*automox.ServerDetails, error) {
+	if err := s.fake.call(ctx, "Servers.Get", id, id); err != nil {
+		return nil, err
+	}
+
+	s.fake.mu.Lock()
+	defer s.fake.mu.Unlock()
+	srv, ok := s.fake.servers[id]
+	if !ok {
+		return nil, notFound("server", id)
+	}
+	srv.Tags = append([]string(nil), srv.Tags...)
+	return &srv, nil
+}
+
+// GetPackages implements automox.ServersService
+func (s *Servers) GetPackages(ctx context.Context, id int64) (*automox.Packages, error) {
+	if err := s.fake.call(ctx, "Servers.GetPackages", id, id); err != nil {
+		return nil, err
+	}
+
+	s.fake.mu.Lock()
+	defer s.fake.mu.Unlock()
+	if _, ok := s.fake.servers[id]; !ok {
+		return nil, notFound("server", id)
+	}
+	pkgs := append(automox.Packages{}, s.fake.packages[id]...)
+	return &pkgs, nil
+}
+
+// GetCommandQueue implements automox.ServersService
+func (s *Servers) GetCommandQueue(ctx context.Context, id int64) (*automox.CommandQueue, error) {
+	if err := s.fake.call(ctx, "Servers.GetCommandQueue", id, id); err != nil {
+		return nil, err
+	}
+
+	s.fake.mu.Lock()
+	defer s.fake.mu.Unlock()
+	if _, ok := s.fake.servers[id]; !ok {
+		return nil, notFound("server", id)
+	}
+	q := append(automox.CommandQueue{}, s.fake.queues[id]...)
+	return &q, nil
+}
+
+// Inventory implements automox.ServersService
+func (s *Servers) Inventory(ctx context.Context, uuid string) (*automox.Inventory, error) {
+	if err := s.fake.call(ctx, "Servers.Inventory", uuid, uuid); err != nil {
+		return nil, err
+	}
+
+	s.fake.mu.Lock()
+	defer s.fake.mu.Unlock()
+	inv, ok := s.fake.inventory[uuid]
+	if !ok {
+		return nil, notFound("device", uuid)
+	}
+	return &inv, nil
+}
+
+// AddTags implements automox.ServersService
+func (s *Servers) AddTags(ctx context.Context, id int64, tags ...string) ([]string, error) {
+	if err := s.fake.call(ctx, "Servers.AddTags", id, id, tags); err != nil {
+		return nil, err
+	}
+	return s.fake.updateTags(id, func(current []string) []string {
+		return shared.MergeTags(current, tags, nil)
+	})
+}
+
+// RemoveTags implements automox.ServersService
+func (s *Servers) RemoveTags(ctx context.Context, id int64, tags ...string) ([]string, error) {
+	if err := s.fake.call(ctx, "Servers.RemoveTags", id, id, tags); err != nil {
+		return nil, err
+	}
+	return s.fake.updateTags(id, func(current []string) []string {
+		return shared.MergeTags(current, nil, tags)
+	})
+}
+
+// SetTags implements automox.ServersService
+func (s *Servers) SetTags(ctx context.Context, id int64, tags ...string) ([]string, error) {
+	if err := s.fake.call(ctx, "Servers.SetTags", id, id, tags); err != nil {
+		return nil, err
+	}
+	return s.fake.updateTags(id, func([]string) []string {
+		return shared.MergeTags(nil, tags, nil)
+	})
+}
+
+// ListTags implements automox.ServersService
+func (s *Servers) ListTags(ctx context.Context) ([]automox.TagCount, error) {
+	if err := s.fake.call(ctx, "Servers.ListTags", nil); err != nil {
+		return nil, err
+	}
+	return s.fake.listServers().Tags(), nil
+}
+
+// listServers returns a copy of every server, ordered by ID
+func (f *Fake) listServers() automox.Servers {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	servers := make(automox.Servers, 0, len(f.servers))
+	for _, id := range sortedIDs(f.servers) {
+		srv := f.servers[id]
+		srv.Tags = append([]string(nil), srv.Tags...)
+		servers = append(servers, srv)
+	}
+	return servers
+}
+
+// updateTags applies modify to a server's tags
+func (f *Fake) updateTags(id int64, modify func([]string) []string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	srv, ok := f.servers[id]
+	if !ok {
+		return nil, notFound("server", id)
+	}
+	srv.Tags = modify(srv.Tags)
+	f.servers[id] = srv
+	return append([]string(nil), srv.Tags...), nil
+}
@@ -0,0 +1,40 @@
+package automoxtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rk295/go-automox/automox"
+)
+
+func TestFanOutOverFake(t *testing.T) {
+	f := New(Fixtures{
+		Servers: automox.Servers{{ID: 1}, {ID: 2}, {ID: 3}},
+		Packages: map[int64]automox.Packages{
+			1: {{Name: "openssl"}},
+			2: {{Name: "curl"}, {Name: "git"}},
+			3: {},
+		},
+	})
+	errDown := errors.New("device 2 unreachable")
+	f.Inject(Fault{Method: "Servers.GetPackages", ID: 2, Err: errDown})
+
+	servers, err := f.Servers().List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := automox.GetPackagesForAll(context.Background(), f.Servers(), servers.IDs(), 2)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if r := results[1]; r.Err != nil || len(*r.Packages) != 1 {
+		t.Errorf("server 1: %+v", r)
+	}
+	if r := results[2]; !errors.Is(r.Err, errDown) {
+		t.Errorf("server 2 error = %v, want the injected fault", r.Err)
+	}
+	if got := len(f.CallsTo("Servers.GetPackages")); got != 3 {
+		t.Errorf("GetPackages called %d times, want 3", got)
+	}
+}
@@ -0,0 +1,137 @@
+package automoxtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"time"
+
+	"github.com/rk295/go-automox/automox"
+	"github.com/rk295/go-automox/automox/internal/shared"
+)
+
+var _ automox.VulnSyncService = (*VulnSync)(nil)
+
+// VulnSync is the fake Vulnerability Sync service
+type VulnSync struct {
+	fake *Fake
+}
+
+// UploadCSV implements automox.VulnSyncService. The new action set counts
+// each row after the header as an issue.
+func (v *VulnSync) UploadCSV(ctx context.Context, filename string, r io.Reader) (*automox.ActionSet, error) {
+	if err := v.fake.call(ctx, "VulnSync.UploadCSV", nil, filename); err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return v.fake.upload(filename, b)
+}
+
+// UploadFindings implements automox.VulnSyncService
+func (v *VulnSync) UploadFindings(ctx context.Context, findings []automox.Finding) (*automox.ActionSet, error) {
+	if err := v.fake.call(ctx, "VulnSync.UploadFindings", nil, findings); err != nil {
+		return nil, err
+	}
+
+	rows := make([]shared.Finding, len(findings))
+	for i, f := range findings {
+		rows[i] = shared.Finding(f)
+	}
+	b, err := shared.EncodeFindings(rows)
+	if err != nil {
+		return nil, err
+	}
+	return v.fake.upload(shared.FindingsFilename, b)
+}
+
+// ListActionSets implements automox.VulnSyncService
+func (v *VulnSync) ListActionSets(ctx context.Context) (automox.ActionSets, error) {
+	if err := v.fake.call(ctx, "VulnSync.ListActionSets", nil); err != nil {
+		return nil, err
+	}
+
+	v.fake.mu.Lock()
+	defer v.fake.mu.Unlock()
+	sets := automox.ActionSets{}
+	for _, id := range sortedIDs(v.fake.actionSets) {
+		sets = append(sets, v.fake.actionSets[id])
+	}
+	return sets, nil
+}
+
+// GetActionSet implements automox.VulnSyncService
+func (v *VulnSync) GetActionSet(ctx context.Context, id int64) (*automox.ActionSet, error) {
+	if err := v.fake.call(ctx, "VulnSync.GetActionSet", id, id); err != nil {
+		return nil, err
+	}
+
+	v.fake.mu.Lock()
+	defer v.fake.mu.Unlock()
+	a, ok := v.fake.actionSets[id]
+	if !ok {
+		return nil, notFound("action set", id)
+	}
+	return &a, nil
+}
+
+// ListSolutions implements automox.VulnSyncService
+func (v *VulnSync) ListSolutions(ctx context.Context, id int64) (automox.ActionSetSolutions, error) {
+	if err := v.fake.call(ctx, "VulnSync.ListSolutions", id, id); err != nil {
+		return nil, err
+	}
+
+	v.fake.mu.Lock()
+	defer v.fake.mu.Unlock()
+	if _, ok := v.fake.actionSets[id]; !ok {
+		return nil, notFound("action set", id)
+	}
+	return append(automox.ActionSetSolutions{}, v.fake.solutions[id]...), nil
+}
+
+// Execute implements automox.VulnSyncService. The actions can be read back
+// with Fake.Executed.
+func (v *VulnSync) Execute(ctx context.Context, id int64, actions []automox.RemediationAction) error {
+	if err := v.fake.call(ctx, "VulnSync.Execute", id, id, actions); err != nil {
+		return err
+	}
+
+	v.fake.mu.Lock()
+	defer v.fake.mu.Unlock()
+	if _, ok := v.fake.actionSets[id]; !ok {
+		return notFound("action set", id)
+	}
+	v.fake.executed[id] = append(v.fake.executed[id], actions...)
+	return nil
+}
+
+// upload creates an action set from an uploaded CSV
+func (f *Fake) upload(filename string, b []byte) (*automox.ActionSet, error) {
+	rows, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	issues := len(rows) - 1
+	if issues < 0 {
+		issues = 0
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := automox.AutomoxTime(time.Now())
+	a := automox.ActionSet{
+		ID:        f.nextID(),
+		Status:    "ready",
+		Source:    automox.ActionSetSource{Name: filename, Type: "upload"},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	a.Statistics.Issues.Total = issues
+	a.Statistics.Issues.Unmatched = issues
+	f.actionSets[a.ID] = a
+	f.uploads[a.ID] = b
+	return &a, nil
+}
@@ -0,0 +1,304 @@
+package automox
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCacheBody is the largest response body kept in the cache
+const maxCacheBody = 8 << 20
+
+// CachedResponse is a GET response held in a CacheStore
+type CachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	// ETag is used to revalidate the response once it has expired
+	ETag string `json:"etag"`
+	// Expires is when the response must next be revalidated
+	Expires time.Time `json:"expires"`
+}
+
+// CacheStore holds cached responses by key. Implementations must be safe
+// for concurrent use.
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, res *CachedResponse)
+	// DeleteMatching removes every entry for whose key match returns true
+	DeleteMatching(match func(key string) bool)
+}
+
+// CacheConfig configures the response cache enabled by WithCache
+type CacheConfig struct {
+	// Store holds the cached responses. Defaults to a MemoryCache.
+	Store CacheStore
+	// TTL is how long responses are served without asking Automox
+	TTL time.Duration
+	// RouteTTL overrides TTL for routes, keyed by templated route such as
+	// /api/servers/{id}. A negative TTL stops the route being cached.
+	RouteTTL map[string]time.Duration
+}
+
+// WithCache caches successful GET responses, keyed by organization and URL.
+// Fresh responses are served without a request. Expired responses carrying
+// an ETag are revalidated with If-None-Match, so an unchanged resource costs
+// only a 304. A successful write to a path drops the cached responses for
+// that path, anything beneath it and the collection it belongs to, so
+// updating a device invalidates its details, packages and the server list.
+func WithCache(cfg CacheConfig) Option {
+	return func(c *Client) {
+		if cfg.Store == nil {
+			cfg.Store = NewMemoryCache()
+		}
+		c.cache = &cfg
+	}
+}
+
+// skipCacheKey is the context key marking requests which bypass the cache
+type skipCacheKey struct{}
+
+// SkipCache returns a context whose requests are always sent to Automox,
+// for reads which must not be stale. Their responses still refresh the
+// cache.
+func SkipCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCacheKey{}, true)
+}
+
+// ttl returns how long responses for route stay fresh
+func (cfg *CacheConfig) ttl(route string) time.Duration {
+	if ttl, ok := cfg.RouteTTL[route]; ok {
+		return ttl
+	}
+	return cfg.TTL
+}
+
+// cacheKey builds the key a request is cached under
+func cacheKey(orgID int64, r *http.Request) string {
+	return fmt.Sprintf("%d %s", orgID, r.URL.RequestURI())
+}
+
+// cacheResponses is the built-in middleware which serves and fills the cache
+func (am *Client) cacheResponses(next RoundTripFunc) RoundTripFunc {
+	return func(r *http.Request) (*http.Response, error) {
+		route := RouteTemplate(r.URL.Path)
+		if r.Method != http.MethodGet {
+			res, err := next(r)
+			if err == nil && res.StatusCode >= 200 && res.StatusCode <= 299 {
+				am.invalidate(r.URL.Path)
+			}
+			return res, err
+		}
+
+		ttl := am.cache.ttl(route)
+		if ttl < 0 {
+			return next(r)
+		}
+
+		key := cacheKey(am.orgID, r)
+		cached, ok := am.cache.Store.Get(key)
+		if skip, _ := r.Context().Value(skipCacheKey{}).(bool); skip {
+			ok = false
+		}
+		if ok && time.Now().Before(cached.Expires) {
+			markFromCache(r)
+			return cached.response(r), nil
+		}
+		if ok && cached.ETag != "" {
+			r.Header.Set("If-None-Match", cached.ETag)
+		}
+
+		res, err := next(r)
+		if err != nil {
+			return res, err
+		}
+
+		if ok && res.StatusCode == http.StatusNotModified {
+			discardBody(res)
+			// The 304's headers, such as the rate limit, are current and
+			// replace those stored
+			if cached.Header == nil {
+				cached.Header = http.Header{}
+			}
+			for k, v := range res.Header {
+				cached.Header[k] = v
+			}
+			cached.Expires = time.Now().Add(ttl)
+			am.cache.Store.Set(key, cached)
+			markFromCache(r)
+			return cached.response(r), nil
+		}
+
+		if res.StatusCode != http.StatusOK {
+			return res, nil
+		}
+
+		body, err := io.ReadAll(io.LimitReader(res.Body, maxCacheBody+1))
+		if err != nil {
+			res.Body.Close()
+			return nil, err
+		}
+		if len(body) > maxCacheBody {
+			// Too big to keep, so hand the caller what was read and the rest
+			res.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), res.Body), res.Body}
+			return res, nil
+		}
+		res.Body.Close()
+		res.Body = io.NopCloser(bytes.NewReader(body))
+
+		am.cache.Store.Set(key, &CachedResponse{
+			StatusCode: res.StatusCode,
+			Header:     res.Header.Clone(),
+			Body:       body,
+			ETag:       res.Header.Get("ETag"),
+			Expires:    time.Now().Add(ttl),
+		})
+		return res, nil
+	}
+}
+
+// markFromCache records on the request's call that it was answered from
+// the cache
+func markFromCache(r *http.Request) {
+	if call := CallFromContext(r.Context()); call != nil {
+		call.FromCache = true
+	}
+}
+
+// invalidate drops cached responses affected by a write to path: the path
+// itself, anything beneath it and its parent collection
+func (am *Client) invalidate(path string) {
+	path = strings.TrimSuffix(path, "/")
+	parent := path[:strings.LastIndex(path, "/")+1]
+	parent = strings.TrimSuffix(parent, "/")
+	org := fmt.Sprintf("%d ", am.orgID)
+
+	am.cache.Store.DeleteMatching(func(key string) bool {
+		if !strings.HasPrefix(key, org) {
+			return false
+		}
+		p := strings.TrimPrefix(key, org)
+		if i := strings.IndexByte(p, '?'); i >= 0 {
+			p = p[:i]
+		}
+		return p == path || strings.HasPrefix(p, path+"/") || p == parent
+	})
+}
+
+// response builds a response to r from the cached copy
+func (c *CachedResponse) response(r *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.StatusCode, http.StatusText(c.StatusCode)),
+		StatusCode:    c.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       r,
+	}
+}
+
+// defaultMemoryCacheEntries is how many responses NewMemoryCache holds
+const defaultMemoryCacheEntries = 10000
+
+// MemoryCache is a CacheStore held in memory, holding a bounded number of
+// responses and evicting the least recently used beyond that. Expired
+// entries are kept for revalidation only if they carry an ETag.
+type MemoryCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]*list.Element
+	// order holds the entries most recently used first
+	order *list.List
+}
+
+// memoryCacheEntry is an entry in a MemoryCache
+type memoryCacheEntry struct {
+	key string
+	res *CachedResponse
+}
+
+// NewMemoryCache returns an empty in-memory cache holding up to 10,000
+// responses
+func NewMemoryCache() *MemoryCache {
+	return NewMemoryCacheSize(defaultMemoryCacheEntries)
+}
+
+// NewMemoryCacheSize returns an empty in-memory cache holding up to max
+// responses. A max of zero or less uses the default.
+func NewMemoryCacheSize(max int) *MemoryCache {
+	if max <= 0 {
+		max = defaultMemoryCacheEntries
+	}
+	return &MemoryCache{max: max, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+// Get implements CacheStore
+func (m *MemoryCache) Get(key string) (*CachedResponse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	res := el.Value.(*memoryCacheEntry).res
+	// An expired response without an ETag cannot be revalidated
+	if res.ETag == "" && !time.Now().Before(res.Expires) {
+		m.remove(el)
+		return nil, false
+	}
+	m.order.MoveToFront(el)
+	// Hand out a copy so callers cannot race on the stored entry
+	c := *res
+	return &c, true
+}
+
+// Set implements CacheStore
+func (m *MemoryCache) Set(key string, res *CachedResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.entries[key]; ok {
+		el.Value.(*memoryCacheEntry).res = res
+		m.order.MoveToFront(el)
+		return
+	}
+	m.entries[key] = m.order.PushFront(&memoryCacheEntry{key: key, res: res})
+	for m.order.Len() > m.max {
+		m.remove(m.order.Back())
+	}
+}
+
+// DeleteMatching implements CacheStore
+func (m *MemoryCache) DeleteMatching(match func(string) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, el := range m.entries {
+		if match(k) {
+			m.remove(el)
+		}
+	}
+}
+
+// Len returns how many responses the cache holds
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+// remove drops an entry, with m.mu held
+func (m *MemoryCache) remove(el *list.Element) {
+	m.order.Remove(el)
+	delete(m.entries, el.Value.(*memoryCacheEntry).key)
+}
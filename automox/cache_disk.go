@@ -0,0 +1,109 @@
+package automox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DiskCache is a CacheStore which keeps each response in its own file in a
+// directory, so the cache survives restarts and can be shared by processes
+// run one after another. Failures to read or write the cache are treated as
+// misses rather than errors.
+type DiskCache struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// diskCacheEntry is the file format of a DiskCache entry
+type diskCacheEntry struct {
+	Key      string          `json:"key"`
+	Response *CachedResponse `json:"response"`
+}
+
+// NewDiskCache returns a cache stored in dir, creating it if needed
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+// path returns the file an entry is stored in. Keys are hashed as they can
+// be longer than a file name may be.
+func (d *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get implements CacheStore
+func (d *DiskCache) Get(key string) (*CachedResponse, bool) {
+	e, err := readDiskCacheEntry(d.path(key))
+	if err != nil || e.Key != key || e.Response == nil {
+		return nil, false
+	}
+	return e.Response, true
+}
+
+// Set implements CacheStore
+func (d *DiskCache) Set(key string, res *CachedResponse) {
+	b, err := json.Marshal(diskCacheEntry{Key: key, Response: res})
+	if err != nil {
+		return
+	}
+
+	// Write then rename so readers never see a partial entry
+	tmp, err := os.CreateTemp(d.dir, "tmp-*")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(b)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := os.Rename(tmp.Name(), d.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// DeleteMatching implements CacheStore
+func (d *DiskCache) DeleteMatching(match func(string) bool) {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		p := filepath.Join(d.dir, f.Name())
+		e, err := readDiskCacheEntry(p)
+		if err != nil || match(e.Key) {
+			os.Remove(p)
+		}
+	}
+}
+
+func readDiskCacheEntry(path string) (*diskCacheEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	e := &diskCacheEntry{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
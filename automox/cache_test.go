@@ -0,0 +1,219 @@
+package automox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingServer counts the requests to each path, answering each with a
+// body naming the path and how many times it has been asked for
+type countingServer struct {
+	mu    sync.Mutex
+	count map[string]int
+}
+
+func (s *countingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if s.count == nil {
+		s.count = map[string]int{}
+	}
+	s.count[r.Method+" "+r.URL.Path]++
+	n := s.count[r.Method+" "+r.URL.Path]
+	s.mu.Unlock()
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"path":%q,"n":%d}`, r.URL.Path, n)
+}
+
+func (s *countingServer) requests(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count[method+" "+path]
+}
+
+type cachedBody struct {
+	Path string `json:"path"`
+	N    int    `json:"n"`
+}
+
+func getCached(t *testing.T, am *Client, path string) cachedBody {
+	t.Helper()
+	var out cachedBody
+	if _, err := am.Do(context.Background(), http.MethodGet, path, nil, nil, &out); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	return out
+}
+
+func TestCacheTTL(t *testing.T) {
+	srv := &countingServer{}
+	am := newTestClient(t, srv.ServeHTTP, WithCache(CacheConfig{
+		TTL:      50 * time.Millisecond,
+		RouteTTL: map[string]time.Duration{"/api/servergroups": -1},
+	}))
+
+	getCached(t, am, "/api/servers")
+	if got := getCached(t, am, "/api/servers"); got.N != 1 {
+		t.Errorf("fresh response fetched again, n = %d", got.N)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if got := getCached(t, am, "/api/servers"); got.N != 2 {
+		t.Errorf("expired response served from cache, n = %d", got.N)
+	}
+
+	// A negative route TTL is never cached
+	getCached(t, am, "/api/servergroups")
+	getCached(t, am, "/api/servergroups")
+	if n := srv.requests(http.MethodGet, "/api/servergroups"); n != 2 {
+		t.Errorf("uncached route fetched %d times, want 2", n)
+	}
+
+	// SkipCache always asks Automox
+	var out cachedBody
+	if _, err := am.Do(SkipCache(context.Background()), http.MethodGet, "/api/servers", nil, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.N != 3 {
+		t.Errorf("SkipCache served from cache, n = %d", out.N)
+	}
+}
+
+func TestCacheRevalidatesWithETag(t *testing.T) {
+	var mu sync.Mutex
+	var requests, notModified int
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"path":"/api/policies","n":1}`)
+	}, WithCache(CacheConfig{TTL: time.Nanosecond}))
+
+	for i := 0; i < 3; i++ {
+		if got := getCached(t, am, "/api/policies"); got.N != 1 || got.Path != "/api/policies" {
+			t.Errorf("call %d decoded %+v", i, got)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if requests != 3 || notModified != 2 {
+		t.Errorf("%d requests with %d not modified, want 3 and 2", requests, notModified)
+	}
+}
+
+func TestCacheInvalidatesOnWrite(t *testing.T) {
+	srv := &countingServer{}
+	am := newTestClient(t, srv.ServeHTTP, WithCache(CacheConfig{TTL: time.Hour}))
+
+	paths := []string{"/api/servers", "/api/servers/1", "/api/servers/1/packages", "/api/servers/2", "/api/policies"}
+	for _, p := range paths {
+		getCached(t, am, p)
+	}
+
+	if _, err := am.Do(context.Background(), http.MethodPut, "/api/servers/1", nil, map[string]string{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		getCached(t, am, p)
+	}
+
+	want := map[string]int{
+		"/api/servers":            2, // the parent collection
+		"/api/servers/1":          2, // the path written
+		"/api/servers/1/packages": 2, // beneath the path written
+		"/api/servers/2":          1,
+		"/api/policies":           1,
+	}
+	for p, n := range want {
+		if got := srv.requests(http.MethodGet, p); got != n {
+			t.Errorf("GET %s sent %d times, want %d", p, got, n)
+		}
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	m := NewMemoryCacheSize(2)
+	fresh := func() *CachedResponse { return &CachedResponse{Expires: time.Now().Add(time.Hour)} }
+
+	m.Set("a", fresh())
+	m.Set("b", fresh())
+	m.Get("a") // a is now more recently used than b
+	m.Set("c", fresh())
+	if _, ok := m.Get("b"); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	if _, ok := m.Get("a"); !ok {
+		t.Error("recently used entry was evicted")
+	}
+	if m.Len() != 2 {
+		t.Errorf("Len = %d, want 2", m.Len())
+	}
+
+	// Expired entries are dropped unless they can be revalidated
+	m = NewMemoryCacheSize(10)
+	m.Set("stale", &CachedResponse{Expires: time.Now().Add(-time.Second)})
+	m.Set("etag", &CachedResponse{ETag: `"v1"`, Expires: time.Now().Add(-time.Second)})
+	if _, ok := m.Get("stale"); ok || m.Len() != 1 {
+		t.Errorf("expired entry without an ETag kept, Len = %d", m.Len())
+	}
+	if _, ok := m.Get("etag"); !ok {
+		t.Error("expired entry with an ETag dropped")
+	}
+}
+
+func TestDiskCacheRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := &CachedResponse{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Etag": {`"v1"`}},
+		Body:       []byte(`[{"id":1}]`),
+		ETag:       `"v1"`,
+		Expires:    time.Now().Add(time.Hour).Round(0),
+	}
+	d.Set("1 /api/servers", res)
+	d.Set("1 /api/servers/1", res)
+	d.Set("2 /api/servers", res)
+
+	// A second store over the same directory sees the entries
+	d2, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := d2.Get("1 /api/servers")
+	if !ok {
+		t.Fatal("entry not found")
+	}
+	if string(got.Body) != string(res.Body) || got.ETag != res.ETag || !got.Expires.Equal(res.Expires) || got.Header.Get("ETag") != `"v1"` {
+		t.Errorf("read back %+v, want %+v", got, res)
+	}
+	if _, ok := d2.Get("1 /api/policies"); ok {
+		t.Error("missing key found")
+	}
+
+	d2.DeleteMatching(func(key string) bool { return key[0] == '1' })
+	for key, want := range map[string]bool{"1 /api/servers": false, "1 /api/servers/1": false, "2 /api/servers": true} {
+		if _, ok := d.Get(key); ok != want {
+			t.Errorf("after delete, %s present = %v, want %v", key, ok, want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package automox
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+var (
+	uuidSegment    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	numericSegment = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Call describes the API call a request belongs to. Middleware can read it
+// with CallFromContext to label logs, traces and metrics.
+type Call struct {
+	// Route is the request path with IDs templated out, such as
+	// /api/servers/{id}/packages
+	Route string
+	// OrgID is the organization the client is scoped to, or zero
+	OrgID int64
+	// Attempts is how many attempts have been made at the call so far,
+	// zero when it was answered from the cache without a request
+	Attempts int
+	// FromCache is whether the response came from the cache, either fresh
+	// or revalidated with a 304
+	FromCache bool
+}
+
+// callKey is the context key holding the *Call of a request
+type callKey struct{}
+
+// CallFromContext returns the call a request's context belongs to, or nil
+// outside of the client's request path
+func CallFromContext(ctx context.Context) *Call {
+	c, _ := ctx.Value(callKey{}).(*Call)
+	return c
+}
+
+// withCall returns ctx carrying a new call for the given path
+func (am *Client) withCall(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, callKey{}, &Call{
+		Route: RouteTemplate(path),
+		OrgID: am.orgID,
+	})
+}
+
+// RouteTemplate replaces the numeric and UUID segments of an API path with
+// {id} and {uuid}, giving a low cardinality name for the route
+func RouteTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		switch {
+		case numericSegment.MatchString(s):
+			segments[i] = "{id}"
+		case uuidSegment.MatchString(s):
+			segments[i] = "{uuid}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -1,106 +1,453 @@
 package automox
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-const apiUrl = "console.automox.com"
+const (
+	apiUrl      = "console.automox.com"
+	accountsURL = "/api/accounts"
+	// maxDrainBody is the most left over response body read to allow the
+	// connection to be reused. Connections with more are closed instead.
+	maxDrainBody = 256 << 10
+	// defaultMaxResponse is the default cap on the size of a response body
+	defaultMaxResponse = 512 << 20
+)
 
 // Client represents a new Automox API client to
 // be utilized for API requests
 type Client struct {
 	// Context to leverage during the lifetime of the client
 	Context context.Context
-	// Token to use for authentication
+	// Token to use for authentication, unless a TokenSource is configured.
+	// It is read once by New; use WithTokenSource to rotate tokens.
 	Token string
+	// tokens supplies the token for each request
+	tokens TokenSource
+	// lastToken is the token most recently sent
+	lastToken atomic.Value
 	// API client to utilize for making HTTP requests
 	client *http.Client
 	// apiURL is the base URL for the Automox API
 	apiURL string
+	// apiScheme is the scheme used to reach apiURL
+	apiScheme string
+	// orgID is the Automox organization requests are scoped to
+	orgID int64
+	// orgUUID is the UUID of the organization, used by newer endpoints
+	orgUUID string
+	// accountID is the UUID of the account, used by account scoped endpoints
+	accountID string
+	// limiter, when set, bounds the rate of requests made by the client
+	limiter *rateLimiter
+	// retries is how many times a failed request may be retried
+	retries int
+	// middleware wraps every request, outermost first
+	middleware []Middleware
+	// callMiddleware wraps every call outside of retries, outermost first
+	callMiddleware []Middleware
+	// transport sends requests through the middleware chain
+	transport RoundTripFunc
+	// logger receives a record of every request, when set
+	logger *slog.Logger
+	// logBodies is how much of each body to log, zero for none
+	logBodies int
+	// metrics receives measurements of every request, when set
+	metrics Metrics
+	// cache holds GET responses, when enabled
+	cache *CacheConfig
+	// maxResponse caps the size of response bodies, zero for the default
+	// and less than zero for no cap
+	maxResponse int64
+	// schemaHook receives differences between responses and their types,
+	// when strict decoding is enabled
+	schemaHook func(context.Context, []SchemaWarning)
+	// deviceLocks serialises read-modify-write updates of a single device,
+	// holding a lock only while a device is being updated
+	deviceLocks   map[int64]*deviceLock
+	deviceLocksMu sync.Mutex
+}
+
+// deviceLock is the lock on one device and the number of updates holding
+// or waiting on it
+type deviceLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Option configures optional behaviour of the Client
+type Option func(*Client)
+
+// WithOrgID scopes requests to the given Automox organization ID. It is
+// required by endpoints which embed the organization in their path.
+func WithOrgID(id int64) Option {
+	return func(c *Client) {
+		c.orgID = id
+	}
+}
+
+// WithOrgUUID sets the UUID of the Automox organization, which newer
+// endpoints such as device inventory use in place of the numeric ID.
+func WithOrgUUID(uuid string) Option {
+	return func(c *Client) {
+		c.orgUUID = uuid
+	}
+}
+
+// WithAccountID sets the UUID of the Automox account. Account scoped
+// endpoints, such as listing zones, live under the account rather than an
+// organization.
+func WithAccountID(id string) Option {
+	return func(c *Client) {
+		c.accountID = id
+	}
+}
+
+// WithMaxResponseSize caps response bodies at n bytes, failing calls whose
+// response is larger with ErrResponseTooLarge. The default is 512MiB,
+// except for lists streamed by ListEach and StreamList, which are uncapped
+// by default as they are never held in memory. Set here, the cap applies
+// to streamed lists as well. Zero or less removes the cap.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) {
+		if n <= 0 {
+			n = -1
+		}
+		c.maxResponse = n
+	}
+}
+
+// WithBaseURL points the client at a different Automox API host, such as an
+// httptest.Server in tests. Only the scheme and host of u are used.
+func WithBaseURL(u *url.URL) Option {
+	return func(c *Client) {
+		c.apiScheme = u.Scheme
+		c.apiURL = u.Host
+	}
+}
+
+// WithRateLimit limits the client to perSecond requests per second, with
+// bursts of up to burst requests. The limit is shared by every goroutine
+// using the client. A rate of zero or less removes the limit.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(c *Client) {
+		if perSecond <= 0 {
+			c.limiter = nil
+			return
+		}
+		c.limiter = newRateLimiter(perSecond, burst)
+	}
 }
 
 // Used if custom client not passed in when NewClient instantiated
 func defaultHTTPClient() *http.Client {
 	return &http.Client{
-		Timeout: time.Minute,
+		Timeout:   time.Minute,
+		Transport: defaultTransport(),
+	}
+}
+
+// defaultTransport keeps connections to Automox alive between requests so
+// bulk walks over many devices do not pay for a TLS handshake per call.
+// HTTP/2 is negotiated where available, and gzip responses are requested
+// and transparently decompressed by the transport.
+func defaultTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   32,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
 	}
 }
 
 // New returns a new Automox API client
-func New(ctx context.Context, token string, client *http.Client) (*Client, error) {
+func New(ctx context.Context, token string, client *http.Client, opts ...Option) (*Client, error) {
 
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	if token == "" {
-		return nil, missingClientConfigErr("Token")
-	}
-
 	// default to HTTP client if one is not provided
 	if client == nil {
 		client = defaultHTTPClient()
 		client.Timeout = time.Minute * 5
 	}
 
-	return &Client{
-		apiURL:  apiUrl,
-		client:  client,
-		Context: ctx,
-		Token:   token,
-	}, nil
+	c := &Client{
+		apiURL:    apiUrl,
+		apiScheme: "https",
+		client:    client,
+		Context:   ctx,
+		Token:     token,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.tokens == nil {
+		if token == "" {
+			return nil, missingClientConfigErr("Token")
+		}
+		c.tokens = StaticToken(token)
+	}
+	c.transport = c.buildTransport()
+
+	return c, nil
+}
+
+// newURL returns an absolute URL for the given API path
+func (am *Client) newURL(path string) *url.URL {
+	return &url.URL{
+		Scheme: am.apiScheme,
+		Host:   am.apiURL,
+		Path:   path,
+	}
+}
+
+// scopeToOrg adds the organization query parameter to u when the client has
+// been configured with an organization ID. Account scoped endpoints span
+// every organization in the account and are left unscoped.
+func (am *Client) scopeToOrg(u *url.URL) {
+	if am.orgID == 0 || strings.HasPrefix(u.Path, accountsURL+"/") {
+		return
+	}
+	q := u.Query()
+	q.Set("o", strconv.FormatInt(am.orgID, 10))
+	u.RawQuery = q.Encode()
+}
+
+// lockDevice locks the device with the given ID for a read-modify-write
+// update, returning the function which unlocks it
+func (am *Client) lockDevice(id int64) func() {
+	am.deviceLocksMu.Lock()
+	if am.deviceLocks == nil {
+		am.deviceLocks = map[int64]*deviceLock{}
+	}
+	l, ok := am.deviceLocks[id]
+	if !ok {
+		l = &deviceLock{}
+		am.deviceLocks[id] = l
+	}
+	l.refs++
+	am.deviceLocksMu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		am.deviceLocksMu.Lock()
+		defer am.deviceLocksMu.Unlock()
+		// The last update of a device drops its lock
+		if l.refs--; l.refs == 0 {
+			delete(am.deviceLocks, id)
+		}
+	}
+}
+
+// accountPath prefixes path with the account scoped API prefix
+func (am *Client) accountPath(path string) (string, error) {
+	if am.accountID == "" {
+		return "", missingOptionErr("account ID")
+	}
+	return fmt.Sprintf("%s/%s%s", accountsURL, am.accountID, path), nil
 }
 
 // makeRequest is used internally by the Automox API client to
 // make an API request and unmarshal into the response interface passed in
 func (am *Client) makeRequest(r *http.Request, v interface{}) (*http.Response, error) {
-	var bearer = "Bearer " + am.Token
-
 	r.Header.Set("Accept", "application/json")
 	r.Header.Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0, post-check=0, pre-check=0")
 	r.Header.Set("Strict-Transport-Security", "max-age=31536000 ; includeSubDomains")
-	r.Header.Set("Authorization", bearer)
 
 	// Replace scheme for unit tests that are using a mock server
 	if os.Getenv("GO_TEST") == "1" {
 		r.URL.Scheme = "http"
 	}
 
-	r.Close = true
+	r = r.WithContext(am.withCall(r.Context(), r.URL.Path))
 
-	res, err := am.client.Do(r)
+	res, err := am.transport(r)
 	if err != nil {
-		return nil, fmt.Errorf("error making %s request to %s", r.Method, r.URL)
+		if res != nil {
+			discardBody(res)
+		}
+		return nil, fmt.Errorf("error making %s request to %s: %w", r.Method, r.URL, err)
 	}
 
+	body := res.Body
 	defer func() {
-		if err := res.Body.Close(); err != nil {
-			panic(err)
+		// Drain anything left unread, such as the trailing newline after a
+		// JSON document, so the connection can be reused. The response has
+		// been decoded by now, and a write it reports has been made, so
+		// failing to close costs only the connection and is logged rather
+		// than failing the call.
+		_, _ = io.Copy(io.Discard, io.LimitReader(body, maxDrainBody))
+		if err := body.Close(); err != nil {
+			am.warnLogger().WarnContext(r.Context(), "closing automox response body", "method", r.Method, "path", r.URL.Path, "error", err)
 		}
 	}()
 
+	if limit := am.responseLimit(v); limit > 0 {
+		res.Body = &cappedBody{ReadCloser: body, remaining: limit}
+	}
+	captureResponse(r.Context(), res)
+
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		v = ErrorResponse{}
+		return res, decodeErrorResponse(res)
 	}
 
-	if v == nil {
+	// Writes commonly answer 201 or 204 with no body at all
+	if v == nil || res.StatusCode == http.StatusNoContent {
 		return res, nil
 	}
 
-	if os.Getenv("GO_DEBUG") == "1" {
-		for k, v := range res.Header {
-			fmt.Printf("%s: %s\n", k, v)
+	if d, ok := v.(responseDecoder); ok {
+		return res, d.decodeResponse(res.Body)
+	}
+
+	if am.schemaHook != nil {
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			return res, err
+		}
+		if err := am.decodeStrict(r.Context(), b, v); err != nil {
+			return res, err
+		}
+		captureTotal(r.Context(), v)
+		return res, nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&v); err != nil && err != io.EOF {
+		return res, err
+	}
+	captureTotal(r.Context(), v)
+	return res, nil
+}
+
+// responseLimit returns the cap on the size of the response decoded into v,
+// zero for none
+func (am *Client) responseLimit(v interface{}) int64 {
+	switch {
+	case am.maxResponse > 0:
+		return am.maxResponse
+	case am.maxResponse < 0:
+		return 0
+	}
+	// Streamed responses are decoded a piece at a time, so their size
+	// costs no memory
+	if _, ok := v.(responseDecoder); ok {
+		return 0
+	}
+	return defaultMaxResponse
+}
+
+// responseDecoder is implemented by response values which decode the body
+// themselves rather than have it unmarshalled in one go, such as when
+// streaming large lists
+type responseDecoder interface {
+	decodeResponse(io.Reader) error
+}
+
+// newRequest builds a request for the given API path. A non-nil body is
+// sent as is if it is an io.Reader, in which case the caller sets the
+// Content-Type, otherwise it is encoded as JSON.
+func (am *Client) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	u := am.newURL(path)
+	u.RawQuery = query.Encode()
+	am.scopeToOrg(u)
+
+	var r io.Reader
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		r = b
+	default:
+		buf, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
 		}
+		r = bytes.NewReader(buf)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
 	}
-	return res, json.NewDecoder(res.Body).Decode(&v)
+	return req, nil
+}
+
+// Do makes a request to an arbitrary API path, for endpoints the library
+// does not yet wrap. It is authenticated and scoped to the client's
+// organization in the same way as the typed services. body is encoded as
+// JSON unless it is an io.Reader, and a successful response is decoded
+// into out if it is non-nil. Error responses are returned as an
+// *ErrorResponse.
+func (am *Client) Do(ctx context.Context, method, path string, query url.Values, body, out interface{}) (*http.Response, error) {
+	req, err := am.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	return am.makeRequest(req, out)
 }
 
 // Servers is the interface between the HTTP client and the Automox servers related endpoints
 func (am *Client) Servers() ServersService {
 	return &ServersClient{client: am}
 }
+
+// VulnSync is the interface between the HTTP client and the Automox
+// Vulnerability Sync (remediations) endpoints
+func (am *Client) VulnSync() VulnSyncService {
+	return &VulnSyncClient{client: am}
+}
+
+// Audit is the interface between the HTTP client and the Automox audit
+// service endpoints
+func (am *Client) Audit() AuditService {
+	return &AuditClient{client: am}
+}
+
+// Accounts is the interface between the HTTP client and the Automox account
+// scoped endpoints
+func (am *Client) Accounts() AccountsService {
+	return &AccountsClient{client: am}
+}
+
+// Policies is the interface between the HTTP client and the Automox policy
+// endpoints
+func (am *Client) Policies() PoliciesService {
+	return &PoliciesClient{client: am}
+}
+
+// ServerGroups is the interface between the HTTP client and the Automox
+// server group endpoints
+func (am *Client) ServerGroups() ServerGroupsService {
+	return &ServerGroupsClient{client: am}
+}
@@ -0,0 +1,324 @@
+package automox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestClient returns a client pointed at a test server running h
+func newTestClient(t *testing.T, h http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	am, err := New(context.Background(), "test-token", srv.Client(), append([]Option{WithBaseURL(u)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return am
+}
+
+func TestDoSendsJSONBody(t *testing.T) {
+	type widget struct {
+		Name string `json:"name"`
+	}
+
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var in widget
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		if in.Name != "sprocket" {
+			t.Errorf("name = %q, want sprocket", in.Name)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"name":"sprocket-2"}`)
+	})
+
+	var out widget
+	if _, err := am.Do(context.Background(), http.MethodPost, "/api/widgets", nil, widget{Name: "sprocket"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "sprocket-2" {
+		t.Errorf("decoded name = %q, want sprocket-2", out.Name)
+	}
+}
+
+func TestDoQuery(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		path  string
+		query url.Values
+		want  url.Values
+	}{
+		{"scoped", "/api/widgets", url.Values{"limit": {"5"}, "tag": {"a", "b"}}, url.Values{"limit": {"5"}, "tag": {"a", "b"}, "o": {"42"}}},
+		{"scoped without a query", "/api/widgets", nil, url.Values{"o": {"42"}}},
+		{"organization overridden", "/api/widgets", url.Values{"o": {"7"}}, url.Values{"o": {"42"}}},
+		{"account scoped", "/api/accounts/acc-1/zones", url.Values{"page": {"2"}}, url.Values{"page": {"2"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					t.Errorf("path = %q, want %q", r.URL.Path, tt.path)
+				}
+				if got := r.URL.Query(); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("query = %v, want %v", got, tt.want)
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}, WithOrgID(42))
+
+			if _, err := am.Do(context.Background(), http.MethodGet, tt.path, tt.query, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestDoSendsReaderBody(t *testing.T) {
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// Readers are sent as is, with the caller's choice of Content-Type
+		if got := r.Header.Get("Content-Type"); got != "" {
+			t.Errorf("Content-Type = %q, want none", got)
+		}
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(b) != "name,os\nweb-01,linux\n" {
+			t.Errorf("body = %q", b)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"imported":1}`)
+	})
+
+	// A nil out discards the response
+	res, err := am.Do(context.Background(), http.MethodPost, "/api/import", nil, strings.NewReader("name,os\nweb-01,linux\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("status = %d", res.StatusCode)
+	}
+}
+
+func TestDoEmptyResponses(t *testing.T) {
+	for _, status := range []int{http.StatusCreated, http.StatusNoContent} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			})
+
+			var out struct{ ID int }
+			res, err := am.Do(context.Background(), http.MethodPost, "/api/widgets", nil, map[string]string{}, &out)
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+			if res.StatusCode != status {
+				t.Errorf("status = %d, want %d", res.StatusCode, status)
+			}
+		})
+	}
+}
+
+func TestDoValidationErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnprocessableEntity} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				_, _ = io.WriteString(w, `{"message":"invalid","errors":{"name":["is required"],"server_groups":"must not be empty"}}`)
+			})
+
+			_, err := am.Do(context.Background(), http.MethodPost, "/api/policies", nil, map[string]string{}, nil)
+			var res *ErrorResponse
+			if !errors.As(err, &res) {
+				t.Fatalf("error = %v, want *ErrorResponse", err)
+			}
+			if res.StatusCode != status || !res.IsValidation() {
+				t.Errorf("status = %d, validation = %v", res.StatusCode, res.IsValidation())
+			}
+			if res.Message != "invalid" {
+				t.Errorf("message = %q", res.Message)
+			}
+			want := map[string][]string{
+				"name":          {"is required"},
+				"server_groups": {"must not be empty"},
+			}
+			if !reflect.DeepEqual(res.Fields, want) {
+				t.Errorf("fields = %v, want %v", res.Fields, want)
+			}
+		})
+	}
+}
+
+func TestDoNonJSONError(t *testing.T) {
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = io.WriteString(w, "<html>upstream gone</html>\n")
+	})
+
+	_, err := am.Do(context.Background(), http.MethodGet, "/api/servers", nil, nil, nil)
+	var res *ErrorResponse
+	if !errors.As(err, &res) {
+		t.Fatalf("error = %v, want *ErrorResponse", err)
+	}
+	if res.StatusCode != http.StatusBadGateway {
+		t.Errorf("status = %d", res.StatusCode)
+	}
+	if res.Message != "<html>upstream gone</html>" {
+		t.Errorf("message = %q", res.Message)
+	}
+}
+
+// faultTransport answers every request with a 200 whose body is body
+type faultTransport struct {
+	body io.ReadCloser
+}
+
+func (t faultTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       t.body,
+		Request:    r,
+	}, nil
+}
+
+// faultyBody is a response body which fails with readErr in place of EOF,
+// and with closeErr on close
+type faultyBody struct {
+	r        io.Reader
+	readErr  error
+	closeErr error
+	closed   bool
+}
+
+func (b *faultyBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err == io.EOF && b.readErr != nil {
+		return n, b.readErr
+	}
+	return n, err
+}
+
+func (b *faultyBody) Close() error {
+	b.closed = true
+	return b.closeErr
+}
+
+func newFaultClient(t *testing.T, body io.ReadCloser, opts ...Option) *Client {
+	t.Helper()
+	am, err := New(context.Background(), "test-token", &http.Client{Transport: faultTransport{body: body}}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return am
+}
+
+func TestDoCloseError(t *testing.T) {
+	errClose := errors.New("connection reset on close")
+	body := &faultyBody{r: strings.NewReader(`{"name":"sprocket"}`), closeErr: errClose}
+	var logs bytes.Buffer
+	am := newFaultClient(t, body, WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	// The response was read in full, so the call succeeds and the failure
+	// to close is logged
+	var out struct{ Name string }
+	if _, err := am.Do(context.Background(), http.MethodPost, "/api/widgets", nil, nil, &out); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if out.Name != "sprocket" {
+		t.Errorf("decoded name = %q", out.Name)
+	}
+	if !body.closed {
+		t.Error("body was not closed")
+	}
+	if !strings.Contains(logs.String(), errClose.Error()) {
+		t.Errorf("close error not logged: %s", logs.String())
+	}
+}
+
+func TestDoReadErrorMidBody(t *testing.T) {
+	errRead := errors.New("unexpected EOF from upstream")
+	body := &faultyBody{r: strings.NewReader(`{"name":"spro`), readErr: errRead, closeErr: errors.New("close")}
+	am := newFaultClient(t, body)
+
+	var out struct{ Name string }
+	_, err := am.Do(context.Background(), http.MethodGet, "/api/widgets", nil, nil, &out)
+	if !errors.Is(err, errRead) {
+		t.Fatalf("error = %v, want the read error", err)
+	}
+	if !body.closed {
+		t.Error("body was not closed")
+	}
+}
+
+func TestDoResponseTooLarge(t *testing.T) {
+	payload := `{"name":"` + strings.Repeat("x", 1024) + `"}`
+	body := &faultyBody{r: strings.NewReader(payload)}
+	am := newFaultClient(t, body, WithMaxResponseSize(512))
+
+	var out struct{ Name string }
+	_, err := am.Do(context.Background(), http.MethodGet, "/api/widgets", nil, nil, &out)
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("error = %v, want ErrResponseTooLarge", err)
+	}
+	if !body.closed {
+		t.Error("body was not closed")
+	}
+
+	// A body of exactly the cap still decodes
+	body = &faultyBody{r: strings.NewReader(payload)}
+	am = newFaultClient(t, body, WithMaxResponseSize(int64(len(payload))))
+	if _, err := am.Do(context.Background(), http.MethodGet, "/api/widgets", nil, nil, &out); err != nil {
+		t.Fatalf("body at the cap: %v", err)
+	}
+}
+
+func TestResponseLimit(t *testing.T) {
+	var out Servers
+	stream := eachServer(func(ServerDetails) error { return nil })
+	for _, tt := range []struct {
+		name string
+		opts []Option
+		v    interface{}
+		want int64
+	}{
+		{"default", nil, &out, defaultMaxResponse},
+		{"default streamed", nil, stream, 0},
+		{"set", []Option{WithMaxResponseSize(1 << 20)}, &out, 1 << 20},
+		{"set streamed", []Option{WithMaxResponseSize(1 << 20)}, stream, 1 << 20},
+		{"removed", []Option{WithMaxResponseSize(0)}, &out, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			am, err := New(context.Background(), "test-token", nil, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := am.responseLimit(tt.v); got != tt.want {
+				t.Errorf("responseLimit = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
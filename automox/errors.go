@@ -1,13 +1,148 @@
 package automox
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
 )
 
+// maxErrorBody caps how much of an error response is read
+const maxErrorBody = 64 << 10
+
+// ErrResponseTooLarge is returned when a response body is larger than the
+// cap set with WithMaxResponseSize
+var ErrResponseTooLarge = errors.New("automox: response body too large")
+
+// cappedBody fails reads once more than remaining bytes have been read
+type cappedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *cappedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// Check for more before failing, so a body of exactly the cap
+		// still reads to a clean EOF
+		var one [1]byte
+		if n, _ := b.ReadCloser.Read(one[:]); n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
 // ErrorResponse represents a Automox API error
 type ErrorResponse struct {
-	Errors []string `json:"errors"`
+	// StatusCode is the HTTP status of the response
+	StatusCode int `json:"-"`
+	// Message is the summary message, when Automox sends one
+	Message string   `json:"message"`
+	Errors  []string `json:"errors"`
+	// Fields holds the validation messages for each invalid field of a
+	// request, as returned with 400 and 422 responses
+	Fields map[string][]string `json:"-"`
+}
+
+func (e *ErrorResponse) Error() string {
+	msgs := []string{}
+	if e.Message != "" {
+		msgs = append(msgs, e.Message)
+	}
+	msgs = append(msgs, e.Errors...)
+
+	fields := make([]string, 0, len(e.Fields))
+	for f := range e.Fields {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	for _, f := range fields {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", f, strings.Join(e.Fields[f], ", ")))
+	}
+
+	if len(msgs) == 0 {
+		return fmt.Sprintf("automox: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("automox: %d %s", e.StatusCode, strings.Join(msgs, "; "))
+}
+
+// IsValidation reports whether the request was rejected as invalid, in
+// which case Fields and Errors describe what to fix
+func (e *ErrorResponse) IsValidation() bool {
+	return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+}
+
+// UnmarshalJSON accepts the shapes Automox uses for errors, which is either
+// a list of messages or an object of messages keyed by field
+func (e *ErrorResponse) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Message string          `json:"message"`
+		Errors  json.RawMessage `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	e.Message = raw.Message
+
+	if len(raw.Errors) == 0 || string(raw.Errors) == "null" {
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw.Errors, &list); err == nil {
+		e.Errors = list
+		return nil
+	}
+
+	var one string
+	if err := json.Unmarshal(raw.Errors, &one); err == nil {
+		e.Errors = []string{one}
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw.Errors, &fields); err != nil {
+		return err
+	}
+	e.Fields = make(map[string][]string, len(fields))
+	for f, v := range fields {
+		var msgs []string
+		if err := json.Unmarshal(v, &msgs); err != nil {
+			var msg string
+			if err := json.Unmarshal(v, &msg); err != nil {
+				msg = string(v)
+			}
+			msgs = []string{msg}
+		}
+		e.Fields[f] = msgs
+	}
+	return nil
+}
+
+// decodeErrorResponse builds the error for an unsuccessful response. Bodies
+// which are not JSON are kept as the message.
+func decodeErrorResponse(res *http.Response) error {
+	errRes := &ErrorResponse{StatusCode: res.StatusCode}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	if err != nil || len(bytes.TrimSpace(body)) == 0 {
+		return errRes
+	}
+
+	if err := json.Unmarshal(body, errRes); err != nil {
+		errRes.Message = strings.TrimSpace(string(body))
+	}
+	return errRes
 }
 
 // Helper to be used for API client config errors
@@ -15,3 +150,8 @@ func missingClientConfigErr(attr string) error {
 	errTxt := fmt.Sprintf("A valid Automox %s is required to create a new API client", attr)
 	return errors.New(errTxt)
 }
+
+// Helper to be used when an endpoint needs client config which was not set
+func missingOptionErr(attr string) error {
+	return fmt.Errorf("the Automox %s must be configured on the client to use this endpoint", attr)
+}
@@ -0,0 +1,67 @@
+package automox
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestErrorResponseDecoding(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		body string
+		want ErrorResponse
+		msg  string
+	}{
+		{
+			name: "no messages",
+			body: `{}`,
+			want: ErrorResponse{StatusCode: 404},
+			msg:  "automox: 404 Not Found",
+		},
+		{
+			name: "message only",
+			body: `{"message":"not allowed","errors":null}`,
+			want: ErrorResponse{StatusCode: 404, Message: "not allowed"},
+			msg:  "automox: 404 not allowed",
+		},
+		{
+			name: "list of errors",
+			body: `{"errors":["first","second"]}`,
+			want: ErrorResponse{StatusCode: 404, Errors: []string{"first", "second"}},
+			msg:  "automox: 404 first; second",
+		},
+		{
+			name: "single error",
+			body: `{"message":"failed","errors":"only one"}`,
+			want: ErrorResponse{StatusCode: 404, Message: "failed", Errors: []string{"only one"}},
+			msg:  "automox: 404 failed; only one",
+		},
+		{
+			name: "field errors",
+			body: `{"errors":{"zone":"unknown","name":["is required","is too long"],"id":3}}`,
+			want: ErrorResponse{StatusCode: 404, Fields: map[string][]string{
+				"zone": {"unknown"},
+				"name": {"is required", "is too long"},
+				"id":   {"3"},
+			}},
+			msg: "automox: 404 id: 3; name: is required, is too long; zone: unknown",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ErrorResponse{StatusCode: 404}
+			if err := json.Unmarshal([]byte(tt.body), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %+v, want %+v", got, tt.want)
+			}
+			if got.Error() != tt.msg {
+				t.Errorf("Error() = %q, want %q", got.Error(), tt.msg)
+			}
+			if got.IsValidation() {
+				t.Error("a 404 reported as a validation error")
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package automox
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	icalUTCFormat      = "20060102T150405Z"
+	icalFloatingFormat = "20060102T150405"
+	icalLineLimit      = 75
+)
+
+// ICalExporter writes the upcoming windows of Automox policies as an
+// RFC 5545 iCalendar feed, one event per run of each policy.
+type ICalExporter struct {
+	// Name of the calendar shown by calendar clients
+	Name string
+	// GroupNames maps server group IDs to names, as returned by
+	// ServerGroups.Names. Unknown groups are shown by ID.
+	GroupNames map[int]string
+	// Types of policy to include. Defaults to patch policies only.
+	Types []string
+	// Location to place schedules which run in device local time. When nil
+	// they are written as floating times, which calendar clients show in
+	// the viewer's own time zone.
+	Location *time.Location
+	// Duration of each event. Defaults to an hour.
+	Duration time.Duration
+	// Recurring writes one event per policy with an RRULE repeating it
+	// until the end of the range, in place of an event for every run.
+	// Schedules in device local time are then written as floating times
+	// whatever the Location, as an RRULE with a fixed offset would drift
+	// when daylight saving time begins or ends.
+	Recurring bool
+}
+
+// PolicyCalendar fetches every policy and server group in the organization
+// and writes their windows between from and to as an iCalendar feed
+func (am *Client) PolicyCalendar(ctx context.Context, w io.Writer, from, to time.Time) error {
+	policies, err := am.Policies().List(ctx)
+	if err != nil {
+		return err
+	}
+
+	groups, err := am.ServerGroups().List(ctx)
+	if err != nil {
+		return err
+	}
+
+	e := ICalExporter{
+		Name:       "Automox patch windows",
+		GroupNames: groups.Names(),
+	}
+	return e.Write(w, policies, from, to)
+}
+
+// Write writes every window of the given policies which falls after from
+// and up to to
+func (e ICalExporter) Write(w io.Writer, policies Policies, from, to time.Time) error {
+	types := e.Types
+	if len(types) == 0 {
+		types = []string{PolicyTypePatch}
+	}
+	duration := e.Duration
+	if duration == 0 {
+		duration = time.Hour
+	}
+
+	bw := bufio.NewWriter(w)
+	cw := &icalWriter{w: bw}
+
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:-//go-automox//Policy Calendar//EN")
+	cw.line("CALSCALE:GREGORIAN")
+	if e.Name != "" {
+		cw.prop("X-WR-CALNAME", e.Name)
+	}
+
+	stamp := time.Now().UTC().Format(icalUTCFormat)
+	for _, p := range policies {
+		if !containsString(types, p.PolicyTypeName) {
+			continue
+		}
+
+		s, err := p.Schedule()
+		if err != nil {
+			return fmt.Errorf("policy %d: %w", p.ID, err)
+		}
+
+		loc := e.Location
+		if s.UTC {
+			loc = time.UTC
+		} else if e.Recurring {
+			loc = nil
+		}
+		floating := loc == nil
+		if floating {
+			// Floating times carry no zone, so project the schedule in UTC
+			// and write the wall clock time as is.
+			loc = time.UTC
+		}
+
+		if e.Recurring {
+			start := s.Next(from, loc)
+			if start.IsZero() || start.After(to) {
+				continue
+			}
+			rule := icalRRule(s, to, floating)
+			e.event(cw, p, s, fmt.Sprintf("policy-%d@automox", p.ID), stamp, start, duration, floating, rule)
+			continue
+		}
+		for _, start := range s.Between(from, to, loc) {
+			uid := fmt.Sprintf("policy-%d-%s@automox", p.ID, start.UTC().Format(icalUTCFormat))
+			e.event(cw, p, s, uid, stamp, start, duration, floating, "")
+		}
+	}
+
+	cw.line("END:VCALENDAR")
+	if cw.err != nil {
+		return cw.err
+	}
+	return bw.Flush()
+}
+
+// event writes a single event for a run of a policy, repeated by rule if
+// it is not empty
+func (e ICalExporter) event(cw *icalWriter, p Policy, s PolicySchedule, uid, stamp string, start time.Time, duration time.Duration, floating bool, rule string) {
+	cw.line("BEGIN:VEVENT")
+	cw.line("UID:" + uid)
+	cw.line("DTSTAMP:" + stamp)
+	if floating {
+		cw.line("DTSTART:" + start.Format(icalFloatingFormat))
+		cw.line("DTEND:" + start.Add(duration).Format(icalFloatingFormat))
+	} else {
+		cw.line("DTSTART:" + start.UTC().Format(icalUTCFormat))
+		cw.line("DTEND:" + start.Add(duration).UTC().Format(icalUTCFormat))
+	}
+	if rule != "" {
+		cw.line("RRULE:" + rule)
+	}
+	cw.prop("SUMMARY", p.Name)
+	cw.prop("DESCRIPTION", e.describe(p, s))
+	cw.prop("CATEGORIES", p.PolicyTypeName)
+	cw.line("TRANSP:TRANSPARENT")
+	cw.line("END:VEVENT")
+}
+
+// icalRRule returns the recurrence rule repeating a schedule until the
+// given time. Week N of a month holds its Nth of each weekday, so weeks map
+// to ordinal BYDAY values.
+func icalRRule(s PolicySchedule, until time.Time, floating bool) string {
+	freq := "WEEKLY"
+	weeks := []int{0}
+	if len(s.Weeks) > 0 && len(s.Weeks) < 5 {
+		freq, weeks = "MONTHLY", s.Weeks
+	}
+
+	var days []string
+	for _, w := range weeks {
+		for _, wd := range s.Days {
+			day := strings.ToUpper(wd.String()[:2])
+			if w > 0 {
+				day = fmt.Sprint(w) + day
+			}
+			days = append(days, day)
+		}
+	}
+
+	end := until.UTC().Format(icalUTCFormat)
+	if floating {
+		end = until.UTC().Format(icalFloatingFormat)
+	}
+	rule := fmt.Sprintf("FREQ=%s;UNTIL=%s;BYDAY=%s", freq, end, strings.Join(days, ","))
+	if len(s.Months) > 0 && len(s.Months) < 12 {
+		months := make([]string, 0, len(s.Months))
+		for _, m := range s.Months {
+			months = append(months, fmt.Sprint(int(m)))
+		}
+		rule += ";BYMONTH=" + strings.Join(months, ",")
+	}
+	return rule
+}
+
+// describe builds the event description for a policy
+func (e ICalExporter) describe(p Policy, s PolicySchedule) string {
+	groups := make([]string, 0, len(p.ServerGroups))
+	for _, id := range p.ServerGroups {
+		if name, ok := e.GroupNames[id]; ok {
+			groups = append(groups, name)
+		} else {
+			groups = append(groups, fmt.Sprintf("group %d", id))
+		}
+	}
+	sort.Strings(groups)
+
+	return fmt.Sprintf("Groups: %s\nDevices: %d\nSchedule: %s", strings.Join(groups, ", "), p.ServerCount, s)
+}
+
+// icalWriter writes content lines, folding them at 75 octets and
+// terminating them with CRLF as RFC 5545 requires
+type icalWriter struct {
+	w   io.Writer
+	err error
+}
+
+// prop writes a property with an escaped text value
+func (cw *icalWriter) prop(name, value string) {
+	cw.line(name + ":" + icalEscape(value))
+}
+
+func (cw *icalWriter) line(l string) {
+	if cw.err != nil {
+		return
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range l {
+		n := utf8.RuneLen(r)
+		if width+n > icalLineLimit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	b.WriteString("\r\n")
+
+	_, cw.err = io.WriteString(cw.w, b.String())
+}
+
+// icalEscape escapes a TEXT value
+func icalEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+func containsString(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package automox
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// dtstamp matches the time a feed was written, which changes every run
+var dtstamp = regexp.MustCompile(`DTSTAMP:\d{8}T\d{6}Z`)
+
+func TestICalFolding(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		line string
+		want string
+	}{
+		{"short", "BEGIN:VEVENT", "BEGIN:VEVENT\r\n"},
+		{"exactly 75 octets", strings.Repeat("a", 75), strings.Repeat("a", 75) + "\r\n"},
+		{"76 octets", strings.Repeat("a", 76), strings.Repeat("a", 75) + "\r\n a\r\n"},
+		{"continuations hold 74 octets", strings.Repeat("a", 150), strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 74) + "\r\n a\r\n"},
+		{"rune straddling the fold", strings.Repeat("a", 74) + "€b", strings.Repeat("a", 74) + "\r\n €b\r\n"},
+		{"rune ending at the fold", strings.Repeat("a", 72) + "€b", strings.Repeat("a", 72) + "€\r\n b\r\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			cw := &icalWriter{w: &b}
+			cw.line(tt.line)
+			if cw.err != nil {
+				t.Fatal(cw.err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("folded:\n got %q\nwant %q", got, tt.want)
+			}
+			checkFolded(t, b.String())
+			if got := strings.ReplaceAll(strings.TrimSuffix(b.String(), "\r\n"), "\r\n ", ""); got != tt.line {
+				t.Errorf("unfolds to %q", got)
+			}
+		})
+	}
+}
+
+// checkFolded checks every content line of an iCalendar stream is CRLF
+// terminated, valid UTF-8 and at most 75 octets
+func checkFolded(t *testing.T, s string) {
+	t.Helper()
+	if !strings.HasSuffix(s, "\r\n") {
+		t.Errorf("stream does not end with CRLF")
+	}
+	for i, l := range strings.Split(strings.TrimSuffix(s, "\r\n"), "\r\n") {
+		if len(l) > icalLineLimit {
+			t.Errorf("line %d is %d octets: %q", i+1, len(l), l)
+		}
+		if !utf8.ValidString(l) {
+			t.Errorf("line %d splits a character: %q", i+1, l)
+		}
+		if strings.ContainsAny(l, "\r\n") {
+			t.Errorf("line %d holds a bare line break: %q", i+1, l)
+		}
+	}
+}
+
+func TestICalEscape(t *testing.T) {
+	for in, want := range map[string]string{
+		"plain":              "plain",
+		"a, b; c":            `a\, b\; c`,
+		`C:\Windows`:         `C:\\Windows`,
+		"one\ntwo\r\nthree":  `one\ntwo\nthree`,
+		`\n is not a break`:  `\\n is not a break`,
+		"Zürich, Straße; 東京": `Zürich\, Straße\; 東京`,
+	} {
+		if got := icalEscape(in); got != want {
+			t.Errorf("icalEscape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestICalExporterGolden(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	policies := Policies{
+		{
+			ID:                   1,
+			Name:                 "Weekday patching, servers; all",
+			PolicyTypeName:       PolicyTypePatch,
+			ScheduleDays:         124,
+			ScheduleWeeksOfMonth: 62,
+			ScheduleMonths:       8190,
+			ScheduleTime:         "02:00",
+			ServerGroups:         []int{10, 11, 12},
+			ServerCount:          42,
+		},
+		{
+			ID:                   2,
+			Name:                 "Monatliche Wartung für die Produktionsserver im Rechenzentrum Zürich – Woche 1 und 3",
+			PolicyTypeName:       PolicyTypePatch,
+			ScheduleDays:         2,
+			ScheduleWeeksOfMonth: 40,
+			ScheduleMonths:       8190,
+			ScheduleTime:         "22:30",
+			Configuration:        []byte(`{"use_scheduled_timezone":true}`),
+			ServerGroups:         []int{11},
+			ServerCount:          7,
+		},
+		{
+			ID:                   3,
+			Name:                 "Sundays in January and July",
+			PolicyTypeName:       PolicyTypePatch,
+			ScheduleDays:         128,
+			ScheduleWeeksOfMonth: 62,
+			ScheduleMonths:       4096 | 64,
+			ScheduleTime:         "06:00",
+		},
+		{
+			ID:             4,
+			Name:           "Not a patch policy",
+			PolicyTypeName: "custom",
+			ScheduleDays:   254,
+			ScheduleTime:   "00:00",
+		},
+	}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		golden string
+		e      ICalExporter
+	}{
+		{"calendar_floating.ics", ICalExporter{Name: "Patch windows, prod"}},
+		{"calendar_zoned.ics", ICalExporter{Name: "Patch windows", Location: ny, Duration: 2 * time.Hour}},
+		{"calendar_recurring.ics", ICalExporter{Name: "Patch windows", Location: ny, Recurring: true, GroupNames: map[int]string{10: "Servers", 11: "Prod, EU"}}},
+	} {
+		t.Run(tt.golden, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := tt.e.Write(&b, policies, from, to); err != nil {
+				t.Fatal(err)
+			}
+			checkFolded(t, b.String())
+			got := dtstamp.ReplaceAll(b.Bytes(), []byte("DTSTAMP:20260101T000000Z"))
+
+			path := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s, rerun with -update and review the diff:\n%s", path, got)
+			}
+		})
+	}
+}
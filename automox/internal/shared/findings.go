@@ -0,0 +1,39 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/csv"
+)
+
+// FindingsFilename is the name findings are uploaded under
+const FindingsFilename = "findings.csv"
+
+// Finding is a scanner finding, with the fields of automox.Finding so
+// that one converts to the other
+type Finding struct {
+	Hostname  string
+	IPAddress string
+	Cve       string
+	Severity  string
+	Title     string
+}
+
+// EncodeFindings encodes findings in the CSV layout Vulnerability Sync
+// accepts for uploads
+func EncodeFindings(findings []Finding) ([]byte, error) {
+	b := &bytes.Buffer{}
+	w := csv.NewWriter(b)
+	if err := w.Write([]string{"Hostname", "IP Address", "CVE ID", "Severity", "Title"}); err != nil {
+		return nil, err
+	}
+	for _, f := range findings {
+		if err := w.Write([]string{f.Hostname, f.IPAddress, f.Cve, f.Severity, f.Title}); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
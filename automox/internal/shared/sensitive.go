@@ -0,0 +1,36 @@
+package shared
+
+import (
+	"net/http"
+	"strings"
+)
+
+// sensitiveHeaders carry credentials
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+// sensitiveParams are query parameters carrying credentials
+var sensitiveParams = map[string]bool{
+	"access_key": true,
+	"api_key":    true,
+	"key":        true,
+	"token":      true,
+	"password":   true,
+}
+
+// IsSensitiveHeader reports whether the named header carries credentials,
+// which are redacted from logs and recordings
+func IsSensitiveHeader(name string) bool {
+	return sensitiveHeaders[http.CanonicalHeaderKey(name)]
+}
+
+// IsSensitiveParam reports whether the named query parameter carries
+// credentials, which are redacted from logs and recordings
+func IsSensitiveParam(name string) bool {
+	return sensitiveParams[strings.ToLower(name)]
+}
@@ -0,0 +1,27 @@
+// Package shared holds what package automox and its fakes in automoxtest
+// both need, without making it part of the public API.
+package shared
+
+import "time"
+
+// These are set by package automox when it is initialised, so automoxtest
+// can build the values its fakes return. They are held as interface{}
+// because this package cannot import automox; callers assert the types
+// noted.
+var (
+	// NewAuditIterator is a func(automox.AuditEvents, error)
+	// *automox.AuditIterator returning an iterator over a fixed list of
+	// events, which then stops with the error, if not nil
+	NewAuditIterator interface{}
+
+	// StreamServers is a func(context.Context, automox.ServersService)
+	// <-chan automox.ServerResult streaming the servers listed by the
+	// service's ListEach, as ServersService.StreamList does
+	StreamServers interface{}
+)
+
+// TruncateDay returns midnight UTC of the day t falls on
+func TruncateDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
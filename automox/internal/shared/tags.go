@@ -0,0 +1,27 @@
+package shared
+
+import "sort"
+
+// MergeTags returns the sorted, de-duplicated union of current and add,
+// less anything in remove, as the tag updates of the servers service apply
+// them. Empty tags are dropped.
+func MergeTags(current, add, remove []string) []string {
+	drop := map[string]bool{}
+	for _, t := range remove {
+		drop[t] = true
+	}
+
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, list := range [][]string{current, add} {
+		for _, t := range list {
+			if t == "" || drop[t] || seen[t] {
+				continue
+			}
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
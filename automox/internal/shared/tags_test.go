@@ -0,0 +1,16 @@
+package shared
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeTags(t *testing.T) {
+	got := MergeTags([]string{"prod", "web", ""}, []string{"db", "web"}, []string{"prod"})
+	if want := []string{"db", "web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeTags = %v, want %v", got, want)
+	}
+	if got := MergeTags(nil, nil, nil); got == nil || len(got) != 0 {
+		t.Errorf("MergeTags of nothing = %#v, want an empty list", got)
+	}
+}
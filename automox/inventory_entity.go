@@ -0,0 +1,114 @@
+package automox
+
+// Inventory is the full hardware and software inventory collected by the
+// agent for a single device
+type Inventory struct {
+	Hardware InventoryHardware   `json:"hardware"`
+	Network  InventoryNetwork    `json:"network"`
+	Security InventorySecurity   `json:"security"`
+	Services []InventoryService  `json:"services"`
+	Users    []InventoryUser     `json:"users"`
+	Software []InventorySoftware `json:"software"`
+}
+
+type InventoryHardware struct {
+	Manufacturer string          `json:"manufacturer"`
+	Model        string          `json:"model"`
+	SerialNumber string          `json:"serial_number"`
+	CPU          InventoryCPU    `json:"cpu"`
+	MemoryBytes  int64           `json:"memory_bytes"`
+	BIOS         InventoryBIOS   `json:"bios"`
+	Disks        []InventoryDisk `json:"disks"`
+}
+
+type InventoryCPU struct {
+	Model    string `json:"model"`
+	Cores    int    `json:"cores"`
+	Threads  int    `json:"threads"`
+	SpeedMHz int    `json:"speed_mhz"`
+}
+
+type InventoryBIOS struct {
+	Vendor      string `json:"vendor"`
+	Version     string `json:"version"`
+	ReleaseDate string `json:"release_date"`
+}
+
+type InventoryDisk struct {
+	Name      string `json:"name"`
+	Model     string `json:"model"`
+	Type      string `json:"type"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+type InventoryNetwork struct {
+	Hostname       string               `json:"hostname"`
+	Domain         string               `json:"domain"`
+	DefaultGateway string               `json:"default_gateway"`
+	DNSServers     []string             `json:"dns_servers"`
+	Interfaces     []InventoryInterface `json:"interfaces"`
+}
+
+type InventoryInterface struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Vendor    string   `json:"vendor"`
+	MAC       string   `json:"mac"`
+	IPv4      []string `json:"ipv4"`
+	IPv6      []string `json:"ipv6"`
+	Connected bool     `json:"connected"`
+}
+
+type InventorySecurity struct {
+	Firewall   InventoryFirewall     `json:"firewall"`
+	Encryption []InventoryEncryption `json:"encryption"`
+	Antivirus  []InventoryAntivirus  `json:"antivirus"`
+}
+
+type InventoryFirewall struct {
+	Enabled  bool                       `json:"enabled"`
+	Profiles []InventoryFirewallProfile `json:"profiles"`
+}
+
+type InventoryFirewallProfile struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+type InventoryEncryption struct {
+	Volume    string `json:"volume"`
+	Method    string `json:"method"`
+	Status    string `json:"status"`
+	Encrypted bool   `json:"encrypted"`
+}
+
+type InventoryAntivirus struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Enabled  bool   `json:"enabled"`
+	UpToDate bool   `json:"up_to_date"`
+}
+
+type InventoryService struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Status      string `json:"status"`
+	StartType   string `json:"start_type"`
+}
+
+type InventoryUser struct {
+	Name      string `json:"name"`
+	FullName  string `json:"full_name"`
+	Domain    string `json:"domain"`
+	IsAdmin   bool   `json:"is_admin"`
+	Disabled  bool   `json:"disabled"`
+	LastLogon string `json:"last_logon"`
+}
+
+type InventorySoftware struct {
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+	Publisher       string `json:"publisher"`
+	InstallDate     string `json:"install_date"`
+	InstallLocation string `json:"install_location"`
+}
@@ -0,0 +1,184 @@
+package automox
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rk295/go-automox/automox/internal/shared"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveFields matches JSON string fields holding credentials, such as
+// api_key, refresh_token or client_secret, including one cut short at the
+// end of a truncated body
+var sensitiveFields = regexp.MustCompile(`("[a-z_]*(?:_key|token|password|secret)"\s*:\s*)"(?:[^"\\]|\\.)*"?`)
+
+// requestIDHeaders are checked in order for the request ID of a response
+var requestIDHeaders = []string{"X-Request-Id", "X-Amzn-Requestid", "X-Amzn-Trace-Id"}
+
+// WithLogger logs every request attempt to logger: successes at debug,
+// error responses at warn and failures to send at error. Credentials are
+// redacted from logged headers, URLs and bodies.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithBodyLogging adds request and response bodies to debug logs, cut
+// short after max bytes
+func WithBodyLogging(max int) Option {
+	return func(c *Client) {
+		c.logBodies = max
+	}
+}
+
+// defaultLogger is used when no logger is configured. GO_DEBUG=1 enables
+// debug logging to stderr, otherwise nothing is logged.
+func defaultLogger() *slog.Logger {
+	if os.Getenv("GO_DEBUG") == "1" {
+		return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	return nil
+}
+
+// warnLogger returns the logger for problems which do not fail a call,
+// falling back to the default logger so they are not lost when no logger
+// is configured
+func (am *Client) warnLogger() *slog.Logger {
+	if am.logger != nil {
+		return am.logger
+	}
+	return slog.Default()
+}
+
+// logRequests is the built-in middleware which logs each attempt
+func (am *Client) logRequests(next RoundTripFunc) RoundTripFunc {
+	return func(r *http.Request) (*http.Response, error) {
+		ctx := r.Context()
+		debug := am.logger.Enabled(ctx, slog.LevelDebug)
+
+		var reqBody string
+		if debug && am.logBodies > 0 && r.GetBody != nil {
+			if b, err := r.GetBody(); err == nil {
+				reqBody = am.redactBody(readCapped(b, am.logBodies))
+				b.Close()
+			}
+		}
+
+		start := time.Now()
+		res, err := next(r)
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("url", redactURL(r.URL)),
+			slog.Int("attempt", RequestAttempt(ctx)),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if debug {
+			attrs = append(attrs, slog.Any("request_headers", redactHeaders(r.Header)))
+			if reqBody != "" {
+				attrs = append(attrs, slog.String("request_body", reqBody))
+			}
+		}
+
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+			am.logger.LogAttrs(ctx, slog.LevelError, "automox request failed", attrs...)
+			return res, err
+		}
+
+		attrs = append(attrs, slog.Int("status", res.StatusCode))
+		if id := responseRequestID(res); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		if debug {
+			attrs = append(attrs, slog.Any("response_headers", redactHeaders(res.Header)))
+			if am.logBodies > 0 {
+				// Read the start of the body for the log and put it back
+				// in front of the rest for the caller to decode
+				head := readCapped(res.Body, am.logBodies)
+				res.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(strings.NewReader(head), res.Body), res.Body}
+				attrs = append(attrs, slog.String("response_body", am.redactBody(head)))
+			}
+		}
+
+		level := slog.LevelDebug
+		if res.StatusCode >= 400 {
+			level = slog.LevelWarn
+		}
+		am.logger.LogAttrs(ctx, level, "automox request", attrs...)
+		return res, nil
+	}
+}
+
+// redactBody removes the client's token and any credential fields from a
+// body before it is logged
+func (am *Client) redactBody(body string) string {
+	for _, t := range []string{am.currentToken(), am.Token} {
+		if t != "" {
+			body = strings.ReplaceAll(body, t, redacted)
+		}
+	}
+	return sensitiveFields.ReplaceAllString(body, `$1"`+redacted+`"`)
+}
+
+// redactHeaders returns a copy of h with credential values replaced
+func redactHeaders(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		if shared.IsSensitiveHeader(k) {
+			out[k] = []string{redacted}
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// redactURL returns u as a string with credential query values replaced
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	changed := false
+	for k := range q {
+		if shared.IsSensitiveParam(k) {
+			q.Set(k, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return u.String()
+	}
+	c := *u
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+// responseRequestID returns the request ID Automox assigned the response
+func responseRequestID(res *http.Response) string {
+	for _, h := range requestIDHeaders {
+		if id := res.Header.Get(h); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// readCapped reads up to max bytes from r
+func readCapped(r io.Reader, max int) string {
+	var b bytes.Buffer
+	_, _ = io.CopyN(&b, r, int64(max))
+	return b.String()
+}
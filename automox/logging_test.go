@@ -0,0 +1,121 @@
+package automox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// logRecords decodes the JSON handler output in b
+func logRecords(t *testing.T, b *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var out []map[string]interface{}
+	dec := json.NewDecoder(b)
+	for dec.More() {
+		var rec map[string]interface{}
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+func TestLoggingRedactsCredentials(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=cookie-secret")
+		// Echo the request body back, along with the token itself
+		b, _ := io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, `{"echo":`+string(b)+`,"seen":"`+strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")+`"}`)
+	}, WithLogger(logger), WithBodyLogging(4096))
+
+	query := url.Values{"API_KEY": {"query-secret"}, "token": {"query-token"}, "o": {"42"}}
+	body := map[string]string{"name": "web", "api_key": "body-secret", "token": "body-token", "client_secret": "body-client"}
+	if _, err := am.Do(context.Background(), http.MethodPost, "/api/widgets", query, body, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	out := logs.String()
+	for _, secret := range []string{"test-token", "query-secret", "query-token", "body-secret", "body-token", "body-client", "cookie-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log output contains %q:\n%s", secret, out)
+		}
+	}
+
+	recs := logRecords(t, &logs)
+	if len(recs) != 1 {
+		t.Fatalf("%d records logged, want 1", len(recs))
+	}
+	rec := recs[0]
+	// What identifies nothing is kept
+	if u, _ := rec["url"].(string); !strings.Contains(u, "o=42") || !strings.Contains(u, "API_KEY="+url.QueryEscape(redacted)) {
+		t.Errorf("url = %q", u)
+	}
+	if b, _ := rec["request_body"].(string); !strings.Contains(b, `"name":"web"`) {
+		t.Errorf("request body = %q", b)
+	}
+	headers, _ := rec["request_headers"].(map[string]interface{})
+	if auth, _ := headers["Authorization"].([]interface{}); len(auth) != 1 || auth[0] != redacted {
+		t.Errorf("Authorization logged as %v", headers["Authorization"])
+	}
+}
+
+func TestLoggingBodyLimit(t *testing.T) {
+	payload := `{"name":"` + strings.Repeat("x", 100) + `"}`
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, payload)
+	}, WithLogger(logger), WithBodyLogging(16))
+
+	var out struct{ Name string }
+	if _, err := am.Do(context.Background(), http.MethodPost, "/api/widgets", nil, map[string]string{"name": strings.Repeat("y", 100)}, &out); err != nil {
+		t.Fatal(err)
+	}
+	// The caller still decodes the whole body
+	if len(out.Name) != 100 {
+		t.Errorf("decoded %d bytes of name, want 100", len(out.Name))
+	}
+
+	rec := logRecords(t, &logs)[0]
+	for _, field := range []string{"request_body", "response_body"} {
+		if b, _ := rec[field].(string); len(b) != 16 {
+			t.Errorf("%s = %q, want the first 16 bytes", field, b)
+		}
+	}
+}
+
+func TestLoggingWithoutDebug(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"token":"body-token"}`)
+	}, WithLogger(logger), WithBodyLogging(4096))
+
+	_, _ = am.Do(context.Background(), http.MethodGet, "/api/widgets", url.Values{"key": {"query-secret"}}, nil, nil)
+
+	recs := logRecords(t, &logs)
+	if len(recs) != 1 || recs[0]["status"] != float64(http.StatusNotFound) {
+		t.Fatalf("records = %v, want the 404 at warn", recs)
+	}
+	// Headers and bodies are only logged at debug
+	for _, field := range []string{"request_headers", "response_headers", "request_body", "response_body"} {
+		if _, ok := recs[0][field]; ok {
+			t.Errorf("%s logged below debug", field)
+		}
+	}
+	if strings.Contains(logs.String(), "query-secret") {
+		t.Errorf("log output contains the query secret:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,54 @@
+package automox
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Metrics receives measurements of the client's use of the API, for export
+// to a monitoring system. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveRequest is called after every attempt at a request. status is
+	// zero when no response was received.
+	ObserveRequest(route, method string, status int, duration time.Duration)
+	// ObserveRateLimitWait is called with the time each attempt spent
+	// waiting on the client's rate limiter
+	ObserveRateLimitWait(wait time.Duration)
+}
+
+// WithMetrics reports every request made by the client to m
+func WithMetrics(m Metrics) Option {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
+// StatusClass groups a status code for use as a low cardinality label:
+// "2xx", "4xx" and so on, or "error" when there was no response
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "error"
+	}
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// recordMetrics is the built-in middleware which times each attempt
+func (am *Client) recordMetrics(next RoundTripFunc) RoundTripFunc {
+	return func(r *http.Request) (*http.Response, error) {
+		route := r.URL.Path
+		if call := CallFromContext(r.Context()); call != nil {
+			route = call.Route
+		}
+
+		start := time.Now()
+		res, err := next(r)
+
+		status := 0
+		if err == nil {
+			status = res.StatusCode
+		}
+		am.metrics.ObserveRequest(route, r.Method, status, time.Since(start))
+		return res, err
+	}
+}
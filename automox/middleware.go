@@ -0,0 +1,257 @@
+package automox
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+)
+
+// RoundTripFunc sends a single API request and returns its response
+type RoundTripFunc func(*http.Request) (*http.Response, error)
+
+// Middleware wraps the sending of every API request, so it can change the
+// request, inspect the response or error, or decide not to send at all.
+// Middleware must not read the response body, which is still to be decoded.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// Hooks are callbacks run around each request. Any may be nil.
+type Hooks struct {
+	// BeforeSend is called before the request is sent. Returning an error
+	// aborts the request with that error.
+	BeforeSend func(*http.Request) error
+	// AfterReceive is called with every response, successful or not.
+	// Returning an error fails the request with that error.
+	AfterReceive func(*http.Request, *http.Response) error
+	// OnError is called when the request could not be sent or a hook failed
+	OnError func(*http.Request, error)
+}
+
+// Middleware returns the hooks as a Middleware
+func (h Hooks) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			res, err := h.roundTrip(next, r)
+			if err != nil && h.OnError != nil {
+				h.OnError(r, err)
+			}
+			return res, err
+		}
+	}
+}
+
+func (h Hooks) roundTrip(next RoundTripFunc, r *http.Request) (*http.Response, error) {
+	if h.BeforeSend != nil {
+		if err := h.BeforeSend(r); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := next(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.AfterReceive != nil {
+		if err := h.AfterReceive(r, res); err != nil {
+			discardBody(res)
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// WithMiddleware adds middleware around every request the client makes.
+// The first middleware given is the outermost. Middleware runs once per
+// attempt, inside any retries configured with WithRetry, and sees the
+// Authorization header already set.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, mw...)
+	}
+}
+
+// WithCallMiddleware adds middleware around each API call as a whole,
+// outside of any retries, so it sees one request and the final response
+// however many attempts were made. Call.Attempts reports how many there
+// were. The first middleware given is the outermost.
+func WithCallMiddleware(mw ...Middleware) Option {
+	return func(c *Client) {
+		c.callMiddleware = append(c.callMiddleware, mw...)
+	}
+}
+
+// WithRetry retries requests which fail with a network error, a 429 or a
+// 502, 503 or 504 up to attempts more times, backing off exponentially or
+// for as long as the Retry-After header asks. Only 429s, which Automox
+// rejects before acting on, are retried for POST and PATCH.
+func WithRetry(attempts int) Option {
+	return func(c *Client) {
+		c.retries = attempts
+	}
+}
+
+// attemptKey is the context key holding the attempt number of a request
+type attemptKey struct{}
+
+// RequestAttempt returns which attempt at a request this is, starting from
+// 1, for use in middleware
+func RequestAttempt(ctx context.Context) int {
+	if n, ok := ctx.Value(attemptKey{}).(int); ok {
+		return n
+	}
+	return 1
+}
+
+// nextAttempt returns ctx numbered as the next attempt at its call, counting
+// every request sent for the call, so the retry after a 401 and those made
+// by WithRetry are told apart
+func nextAttempt(ctx context.Context) context.Context {
+	n, _ := ctx.Value(attemptKey{}).(int)
+	call := CallFromContext(ctx)
+	if call != nil {
+		n = call.Attempts
+	}
+	n++
+	if call != nil {
+		call.Attempts = n
+	}
+	return context.WithValue(ctx, attemptKey{}, n)
+}
+
+// buildTransport composes the built-in and configured middleware around
+// the HTTP client
+func (am *Client) buildTransport() RoundTripFunc {
+	rt := RoundTripFunc(am.client.Do)
+	if am.logger == nil {
+		am.logger = defaultLogger()
+	}
+	if am.logger != nil {
+		rt = am.logRequests(rt)
+	}
+	if am.metrics != nil {
+		rt = am.recordMetrics(rt)
+	}
+	rt = am.rateLimit(rt)
+	for i := len(am.middleware) - 1; i >= 0; i-- {
+		rt = am.middleware[i](rt)
+	}
+	rt = am.authorize(rt)
+	rt = am.retry(rt)
+	if am.cache != nil {
+		rt = am.cacheResponses(rt)
+	}
+	for i := len(am.callMiddleware) - 1; i >= 0; i-- {
+		rt = am.callMiddleware[i](rt)
+	}
+	return rt
+}
+
+// rateLimit waits on the client's rate limiter, if any, before each attempt
+func (am *Client) rateLimit(next RoundTripFunc) RoundTripFunc {
+	return func(r *http.Request) (*http.Response, error) {
+		if am.limiter != nil {
+			wait, err := am.limiter.Wait(r.Context())
+			if am.metrics != nil {
+				am.metrics.ObserveRateLimitWait(wait)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return next(r)
+	}
+}
+
+// retry resends retryable failures as configured by WithRetry
+func (am *Client) retry(next RoundTripFunc) RoundTripFunc {
+	return func(r *http.Request) (*http.Response, error) {
+		ctx := r.Context()
+		for attempt := 1; ; attempt++ {
+			ctx = nextAttempt(ctx)
+			req := r.WithContext(ctx)
+			if attempt > 1 && r.GetBody != nil {
+				body, err := r.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+
+			res, err := next(req)
+			if attempt > am.retries || !shouldRetry(r, res, err) {
+				return res, err
+			}
+			// Bodies which cannot be replayed cannot be retried
+			if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+				return res, err
+			}
+
+			delay := retryDelay(attempt, res)
+			if res != nil {
+				discardBody(res)
+			}
+
+			t := time.NewTimer(delay)
+			select {
+			case <-t.C:
+			case <-r.Context().Done():
+				t.Stop()
+				return nil, r.Context().Err()
+			}
+		}
+	}
+}
+
+// shouldRetry reports whether a failed attempt is worth repeating
+func shouldRetry(r *http.Request, res *http.Response, err error) bool {
+	if err != nil {
+		return r.Context().Err() == nil && isIdempotent(r.Method)
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(r.Method)
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns how long to wait before the next attempt, preferring
+// the server's Retry-After
+func retryDelay(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && s >= 0 {
+			return time.Duration(s) * time.Second
+		}
+	}
+
+	d := retryBaseDelay << uint(attempt-1)
+	if d > retryMaxDelay || d <= 0 {
+		d = retryMaxDelay
+	}
+	// Up to 20% jitter so concurrent callers do not retry in lock step
+	return d - time.Duration(rand.Int63n(int64(d)/5))
+}
+
+// discardBody drains and closes a response body which will not be decoded
+func discardBody(res *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxDrainBody))
+	_ = res.Body.Close()
+}
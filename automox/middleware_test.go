@@ -0,0 +1,193 @@
+package automox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// statusServer answers each request with the next of statuses, repeating
+// the last, and counts the requests
+type statusServer struct {
+	mu         sync.Mutex
+	statuses   []int
+	retryAfter string
+	requests   int
+}
+
+func (s *statusServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status := s.statuses[0]
+	if len(s.statuses) > 1 {
+		s.statuses = s.statuses[1:]
+	}
+	s.requests++
+	s.mu.Unlock()
+
+	_, _ = io.Copy(io.Discard, r.Body)
+	if s.retryAfter != "" {
+		w.Header().Set("Retry-After", s.retryAfter)
+	}
+	w.WriteHeader(status)
+}
+
+func TestRetry(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		method   string
+		body     interface{}
+		statuses []int
+		requests int
+	}{
+		{"429 retried", http.MethodGet, nil, []int{429, 429, 200}, 3},
+		{"503 retried", http.MethodGet, nil, []int{503, 200}, 2},
+		{"gives up", http.MethodGet, nil, []int{503}, 3},
+		{"404 not retried", http.MethodGet, nil, []int{404, 200}, 1},
+		{"POST retried after 429", http.MethodPost, map[string]string{}, []int{429, 200}, 2},
+		{"POST not retried after 503", http.MethodPost, map[string]string{}, []int{503, 200}, 1},
+		{"PUT retried after 503", http.MethodPut, map[string]string{}, []int{503, 200}, 2},
+		{"non-replayable body", http.MethodPost, io.MultiReader(strings.NewReader("{}")), []int{429, 200}, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &statusServer{statuses: tt.statuses, retryAfter: "0"}
+			am := newTestClient(t, srv.ServeHTTP, WithRetry(2))
+
+			_, _ = am.Do(context.Background(), tt.method, "/api/servers", nil, tt.body, nil)
+			if srv.requests != tt.requests {
+				t.Errorf("%d requests sent, want %d", srv.requests, tt.requests)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		res := &http.Response{StatusCode: status, Header: http.Header{"Retry-After": {"7"}}}
+		if got := retryDelay(1, res); got != 7*time.Second {
+			t.Errorf("%d with Retry-After 7: delay = %v", status, got)
+		}
+	}
+
+	// Without Retry-After the delay backs off, with jitter, up to the cap
+	res := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}
+	for attempt, want := range map[int]time.Duration{1: retryBaseDelay, 3: 4 * retryBaseDelay, 40: retryMaxDelay} {
+		if got := retryDelay(attempt, res); got > want || got < want*4/5 {
+			t.Errorf("attempt %d: delay = %v, want about %v", attempt, got, want)
+		}
+	}
+}
+
+func TestRetryCancelledDuringBackoff(t *testing.T) {
+	srv := &statusServer{statuses: []int{http.StatusTooManyRequests}, retryAfter: "30"}
+	am := newTestClient(t, srv.ServeHTTP, WithRetry(3))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := am.Do(ctx, http.MethodGet, "/api/servers", nil, nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want the context's", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("returned after %v, not when cancelled", d)
+	}
+	if srv.requests != 1 {
+		t.Errorf("%d requests sent, want 1", srv.requests)
+	}
+}
+
+func TestHooksOrder(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	record := func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, fmt.Sprintf(format, args...))
+	}
+
+	errStop := errors.New("stop")
+	srv := &statusServer{statuses: []int{429, 200, 500}, retryAfter: "0"}
+	am := newTestClient(t, srv.ServeHTTP, WithRetry(1),
+		WithCallMiddleware(Hooks{
+			BeforeSend: func(r *http.Request) error {
+				record("call before")
+				return nil
+			},
+			AfterReceive: func(r *http.Request, res *http.Response) error {
+				record("call after %d in %d attempts", res.StatusCode, CallFromContext(r.Context()).Attempts)
+				return nil
+			},
+		}.Middleware()),
+		WithMiddleware(Hooks{
+			BeforeSend: func(r *http.Request) error {
+				record("before %d", RequestAttempt(r.Context()))
+				return nil
+			},
+			AfterReceive: func(r *http.Request, res *http.Response) error {
+				record("after %d", res.StatusCode)
+				if res.StatusCode == http.StatusInternalServerError {
+					return errStop
+				}
+				return nil
+			},
+			OnError: func(r *http.Request, err error) {
+				record("error %v", err)
+			},
+		}.Middleware()),
+	)
+
+	if _, err := am.Do(context.Background(), http.MethodGet, "/api/servers", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	// An error from a hook is retried like a network error, so POST, which
+	// is not, keeps the events to a single attempt
+	if _, err := am.Do(context.Background(), http.MethodPost, "/api/servers", nil, nil, nil); !errors.Is(err, errStop) {
+		t.Errorf("error = %v, want the AfterReceive error", err)
+	}
+
+	want := []string{
+		"call before",
+		"before 1", "after 429",
+		"before 2", "after 200",
+		"call after 200 in 2 attempts",
+		"call before",
+		"before 1", "after 500", "error stop",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events:\n got %q\nwant %q", events, want)
+	}
+}
+
+func TestAttemptsCountTokenRefresh(t *testing.T) {
+	srv := &statusServer{statuses: []int{401, 429, 200}, retryAfter: "0"}
+	var attempts []int
+	var call *Call
+	am := newTestClient(t, srv.ServeHTTP, WithRetry(1),
+		WithTokenSource(&rotatingToken{tokens: []string{"old", "new"}}),
+		WithMiddleware(func(next RoundTripFunc) RoundTripFunc {
+			return func(r *http.Request) (*http.Response, error) {
+				attempts = append(attempts, RequestAttempt(r.Context()))
+				call = CallFromContext(r.Context())
+				return next(r)
+			}
+		}),
+	)
+
+	var res Response
+	if _, err := am.Do(CaptureResponse(context.Background(), &res), http.MethodGet, "/api/servers", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(attempts, want) {
+		t.Errorf("attempts = %v, want %v", attempts, want)
+	}
+	if call.Attempts != 3 || res.Attempts != 3 {
+		t.Errorf("call attempts = %d, response attempts = %d, want 3", call.Attempts, res.Attempts)
+	}
+}
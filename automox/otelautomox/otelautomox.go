@@ -0,0 +1,123 @@
+// Package otelautomox traces calls made by the Automox API client with
+// OpenTelemetry.
+//
+//	api, err := automox.New(ctx, token, nil,
+//		automox.WithCallMiddleware(otelautomox.Middleware()),
+//	)
+//
+// Each API call gets a client span named after its method and templated
+// route, such as "GET /api/servers/{id}/packages", parented by any span in
+// the request's context. Retries are counted on the span rather than given
+// spans of their own.
+package otelautomox
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rk295/go-automox/automox"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/rk295/go-automox/automox/otelautomox"
+
+// Attribute keys set on every span
+const (
+	AttrRetryCount = attribute.Key("automox.retry_count")
+	AttrOrgID      = attribute.Key("automox.org_id")
+
+	attrMethod     = attribute.Key("http.request.method")
+	attrRoute      = attribute.Key("url.template")
+	attrURL        = attribute.Key("url.full")
+	attrServer     = attribute.Key("server.address")
+	attrStatusCode = attribute.Key("http.response.status_code")
+)
+
+type config struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// Option configures the tracing middleware
+type Option func(*config)
+
+// WithTracerProvider sets the provider spans are created with. Defaults to
+// the global provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = tp
+	}
+}
+
+// WithPropagator sets the propagator used to inject trace context into
+// outgoing requests. Defaults to the global propagator.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = p
+	}
+}
+
+// Middleware returns middleware which traces each API call. Register it
+// with automox.WithCallMiddleware so a span covers every retry of a call.
+func Middleware(opts ...Option) automox.Middleware {
+	cfg := config{
+		provider:   otel.GetTracerProvider(),
+		propagator: otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	tracer := cfg.provider.Tracer(instrumentationName)
+
+	return func(next automox.RoundTripFunc) automox.RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			route := r.URL.Path
+			call := automox.CallFromContext(r.Context())
+			if call != nil {
+				route = call.Route
+			}
+
+			ctx, span := tracer.Start(r.Context(), fmt.Sprintf("%s %s", r.Method, route),
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					attrMethod.String(r.Method),
+					attrRoute.String(route),
+					attrURL.String(r.URL.Redacted()),
+					attrServer.String(r.URL.Hostname()),
+				),
+			)
+			defer span.End()
+
+			r = r.WithContext(ctx)
+			cfg.propagator.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+			res, err := next(r)
+
+			if call != nil {
+				// A call answered from the cache makes no attempts at all
+				if call.Attempts > 0 {
+					span.SetAttributes(AttrRetryCount.Int(call.Attempts - 1))
+				}
+				if call.OrgID != 0 {
+					span.SetAttributes(AttrOrgID.Int64(call.OrgID))
+				}
+			}
+
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return res, err
+			}
+
+			span.SetAttributes(attrStatusCode.Int(res.StatusCode))
+			if res.StatusCode >= 400 {
+				span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+			}
+			return res, nil
+		}
+	}
+}
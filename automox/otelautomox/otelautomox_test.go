@@ -0,0 +1,66 @@
+package otelautomox
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rk295/go-automox/automox"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddlewareRetryCount(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `[]`)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	api, err := automox.New(context.Background(), "test-token", srv.Client(),
+		automox.WithBaseURL(u),
+		automox.WithCache(automox.CacheConfig{TTL: time.Minute}),
+		automox.WithCallMiddleware(Middleware(WithTracerProvider(tp))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second call is answered from the cache
+	for i := 0; i < 2; i++ {
+		if _, err := api.Servers().GetPackages(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("got %d spans, want 2", len(ended))
+	}
+	if got, ok := attrValue(ended[0].Attributes(), AttrRetryCount); !ok || got.AsInt64() != 0 {
+		t.Errorf("retry count = %v, want 0", got.Emit())
+	}
+	if got, ok := attrValue(ended[1].Attributes(), AttrRetryCount); ok {
+		t.Errorf("retry count %v set on a cache hit", got.Emit())
+	}
+}
+
+func attrValue(attrs []attribute.KeyValue, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
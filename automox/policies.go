@@ -0,0 +1,102 @@
+package automox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+const policiesURL = "/api/policies"
+
+// PoliciesService is an interface for interacting with the policy endpoints
+// of the Automox API
+type PoliciesService interface {
+	List(context.Context) (Policies, error)
+	Get(context.Context, int64) (*Policy, error)
+	Create(context.Context, Policy) (*Policy, error)
+	UploadFile(context.Context, int64, string, io.Reader) error
+}
+
+// PoliciesClient facilitates requests with the Automox policies
+type PoliciesClient struct {
+	client *Client
+}
+
+// List all policies in the organization
+func (c *PoliciesClient) List(ctx context.Context) (Policies, error) {
+	req, err := c.client.newRequest(ctx, http.MethodGet, policiesURL, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Policies{}
+	if _, err := c.client.makeRequest(req, res); err != nil {
+		return nil, err
+	}
+	return *res, nil
+}
+
+// Get a specific policy by ID
+func (c *PoliciesClient) Get(ctx context.Context, id int64) (*Policy, error) {
+	req, err := c.client.newRequest(ctx, http.MethodGet, fmt.Sprintf("%s/%d", policiesURL, id), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Policy{}
+	if _, err := c.client.makeRequest(req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Create a new policy in the organization, returning it as stored
+func (c *PoliciesClient) Create(ctx context.Context, p Policy) (*Policy, error) {
+	if c.client.orgID == 0 {
+		return nil, missingOptionErr("organization ID")
+	}
+	p.OrganizationID = c.client.orgID
+
+	req, err := c.client.newRequest(ctx, http.MethodPost, policiesURL, nil, p)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Policy{}
+	if _, err := c.client.makeRequest(req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UploadFile attaches a file, such as an installer, to a policy. The file
+// is streamed as it is read rather than held in memory, so a failed upload
+// is not retried.
+func (c *PoliciesClient) UploadFile(ctx context.Context, id int64, filename string, r io.Reader) error {
+	pr, pw := io.Pipe()
+	// Closing the reader stops the writer if the request is never sent
+	defer pr.Close()
+
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, err := mw.CreateFormFile("file", filename)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	req, err := c.client.newRequest(ctx, http.MethodPost, fmt.Sprintf("%s/%d/files", policiesURL, id), nil, pr)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	_, err = c.client.makeRequest(req, nil)
+	return err
+}
@@ -0,0 +1,61 @@
+package automox
+
+import "encoding/json"
+
+// Policy type names, as found in Policy.PolicyTypeName
+const (
+	PolicyTypePatch            = "patch"
+	PolicyTypeRequiredSoftware = "required_software"
+	PolicyTypeCustom           = "custom"
+)
+
+// OS families which policies can target
+const (
+	OSWindows = "Windows"
+	OSMac     = "Mac"
+	OSLinux   = "Linux"
+)
+
+type Policies []Policy
+
+// Policy is an Automox policy. Configuration differs by policy type, so it
+// is left for the caller to decode.
+type Policy struct {
+	ID                   int             `json:"id,omitempty"`
+	Name                 string          `json:"name"`
+	PolicyTypeName       string          `json:"policy_type_name"`
+	OrganizationID       int64           `json:"organization_id"`
+	Configuration        json.RawMessage `json:"configuration"`
+	ScheduleDays         int             `json:"schedule_days"`
+	ScheduleWeeksOfMonth int             `json:"schedule_weeks_of_month"`
+	ScheduleMonths       int             `json:"schedule_months"`
+	ScheduleTime         string          `json:"schedule_time"`
+	ServerGroups         []int           `json:"server_groups"`
+	ServerCount          int             `json:"server_count,omitempty"`
+	Notes                string          `json:"notes"`
+	CreateTime           AutomoxTime     `json:"create_time,omitempty"`
+	NextRemediation      AutomoxTime     `json:"next_remediation,omitempty"`
+}
+
+// RequiredSoftwareConfiguration is the configuration of a required software
+// policy. Automox checks for PackageName at PackageVersion on each device
+// and runs InstallationCode where it is missing.
+type RequiredSoftwareConfiguration struct {
+	OsFamily             string         `json:"os_family"`
+	PackageName          string         `json:"package_name"`
+	PackageVersion       string         `json:"package_version"`
+	InstallationCode     string         `json:"installation_code"`
+	InstallerURL         string         `json:"installer_url,omitempty"`
+	Filename             string         `json:"filename,omitempty"`
+	DeviceFiltersEnabled bool           `json:"device_filters_enabled"`
+	DeviceFilters        []DeviceFilter `json:"device_filters,omitempty"`
+	MissedPatchWindow    bool           `json:"missed_patch_window"`
+	UseScheduledTimezone bool           `json:"use_scheduled_timezone"`
+}
+
+// DeviceFilter narrows the devices in a policy's groups it applies to
+type DeviceFilter struct {
+	Field string        `json:"field"`
+	Op    string        `json:"op"`
+	Value []interface{} `json:"value"`
+}
@@ -0,0 +1,218 @@
+package automox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// RequiredSoftwarePolicy builds required software policies which install a
+// package wherever it is missing. Automox scopes each required software
+// policy to a single OS family, so one policy is created for every OS given
+// an install command.
+//
+//	policies, err := automox.NewRequiredSoftwarePolicy("Acme Agent").
+//		Package("Acme Agent", "1.4.2").
+//		Installer("dist/acme-agent-1.4.2.msi").
+//		InstallCommand(automox.OSWindows, `msiexec /i acme-agent-1.4.2.msi /qn`).
+//		Groups(12, 34).
+//		Create(ctx, api.Policies())
+type RequiredSoftwarePolicy struct {
+	name         string
+	notes        string
+	packageName  string
+	version      string
+	installer    string
+	installerURL string
+	commands     map[string]string
+	groups       []int
+	devices      []interface{}
+	schedule     *PolicySchedule
+}
+
+// NewRequiredSoftwarePolicy starts building required software policies
+// with the given name
+func NewRequiredSoftwarePolicy(name string) *RequiredSoftwarePolicy {
+	return &RequiredSoftwarePolicy{
+		name:     name,
+		commands: map[string]string{},
+	}
+}
+
+// Package sets the package name and version Automox checks devices for.
+// Devices already reporting this version are left alone.
+func (b *RequiredSoftwarePolicy) Package(name, version string) *RequiredSoftwarePolicy {
+	b.packageName = name
+	b.version = version
+	return b
+}
+
+// Installer uploads the local file at path to each policy on creation, so
+// install commands can refer to it by its base name
+func (b *RequiredSoftwarePolicy) Installer(path string) *RequiredSoftwarePolicy {
+	b.installer = path
+	return b
+}
+
+// InstallerURL has devices fetch the installer from url instead of an
+// uploaded file
+func (b *RequiredSoftwarePolicy) InstallerURL(url string) *RequiredSoftwarePolicy {
+	b.installerURL = url
+	return b
+}
+
+// InstallCommand sets the command run to install the package on the given
+// OS family, one of OSWindows, OSMac or OSLinux
+func (b *RequiredSoftwarePolicy) InstallCommand(osFamily, command string) *RequiredSoftwarePolicy {
+	b.commands[osFamily] = command
+	return b
+}
+
+// Groups targets the policy at the given server groups
+func (b *RequiredSoftwarePolicy) Groups(ids ...int) *RequiredSoftwarePolicy {
+	b.groups = append(b.groups, ids...)
+	return b
+}
+
+// Devices further limits the policy to the given devices within its groups
+func (b *RequiredSoftwarePolicy) Devices(ids ...int) *RequiredSoftwarePolicy {
+	for _, id := range ids {
+		b.devices = append(b.devices, id)
+	}
+	return b
+}
+
+// Schedule sets when the policies run. Without a schedule they only run
+// when triggered manually.
+func (b *RequiredSoftwarePolicy) Schedule(s PolicySchedule) *RequiredSoftwarePolicy {
+	b.schedule = &s
+	return b
+}
+
+// Notes sets the notes shown alongside the policy in the console
+func (b *RequiredSoftwarePolicy) Notes(notes string) *RequiredSoftwarePolicy {
+	b.notes = notes
+	return b
+}
+
+// Build returns the policies described by the builder without creating
+// them, ordered by OS family. It checks that the installer file exists.
+func (b *RequiredSoftwarePolicy) Build() (Policies, error) {
+	if b.name == "" {
+		return nil, errors.New("required software policy needs a name")
+	}
+	if b.packageName == "" || b.version == "" {
+		return nil, errors.New("required software policy needs a package name and version")
+	}
+	if len(b.commands) == 0 {
+		return nil, errors.New("required software policy needs at least one install command")
+	}
+	if b.installer != "" && b.installerURL != "" {
+		return nil, errors.New("required software policy takes an installer file or URL, not both")
+	}
+	// Check the installer now, rather than after the policies exist
+	if b.installer != "" {
+		fi, err := os.Stat(b.installer)
+		if err != nil {
+			return nil, fmt.Errorf("required software policy installer: %w", err)
+		}
+		if !fi.Mode().IsRegular() {
+			return nil, fmt.Errorf("required software policy installer %s is not a file", b.installer)
+		}
+	}
+
+	osFamilies := make([]string, 0, len(b.commands))
+	for family := range b.commands {
+		osFamilies = append(osFamilies, family)
+	}
+	sort.Strings(osFamilies)
+
+	var filename string
+	if b.installer != "" {
+		filename = filepath.Base(b.installer)
+	}
+
+	policies := make(Policies, 0, len(osFamilies))
+	for _, family := range osFamilies {
+		cfg := RequiredSoftwareConfiguration{
+			OsFamily:          family,
+			PackageName:       b.packageName,
+			PackageVersion:    b.version,
+			InstallationCode:  b.commands[family],
+			InstallerURL:      b.installerURL,
+			Filename:          filename,
+			MissedPatchWindow: true,
+		}
+		if b.schedule != nil {
+			cfg.UseScheduledTimezone = b.schedule.UTC
+		}
+		if len(b.devices) > 0 {
+			cfg.DeviceFiltersEnabled = true
+			cfg.DeviceFilters = []DeviceFilter{{Field: "device-id", Op: "in", Value: b.devices}}
+		}
+
+		raw, err := json.Marshal(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		name := b.name
+		if len(osFamilies) > 1 {
+			name = fmt.Sprintf("%s (%s)", b.name, family)
+		}
+
+		p := Policy{
+			Name:           name,
+			PolicyTypeName: PolicyTypeRequiredSoftware,
+			Configuration:  raw,
+			ServerGroups:   b.groups,
+			Notes:          b.notes,
+		}
+		if b.schedule != nil {
+			p.SetSchedule(*b.schedule)
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// Create creates the policies and uploads the installer to each of them,
+// returning the policies as stored by Automox
+func (b *RequiredSoftwarePolicy) Create(ctx context.Context, svc PoliciesService) (Policies, error) {
+	policies, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	created := make(Policies, 0, len(policies))
+	for _, p := range policies {
+		res, err := svc.Create(ctx, p)
+		if err != nil {
+			return created, err
+		}
+		created = append(created, *res)
+
+		if b.installer == "" {
+			continue
+		}
+		if err := b.upload(ctx, svc, int64(res.ID)); err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// upload sends the installer file to the policy with the given ID
+func (b *RequiredSoftwarePolicy) upload(ctx context.Context, svc PoliciesService, id int64) error {
+	f, err := os.Open(b.installer)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return svc.UploadFile(ctx, id, filepath.Base(b.installer), f)
+}
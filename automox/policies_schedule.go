@@ -0,0 +1,249 @@
+package automox
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// scheduleSearchDays bounds how far ahead Next looks for an occurrence.
+// Rare combinations such as the fifth week of February can be years apart.
+const scheduleSearchDays = 366 * 8
+
+// PolicySchedule is the decoded form of a policy's schedule bitmasks.
+//
+// Automox stores schedules as bitmasks with a trailing unused bit:
+// schedule_days runs Sunday (bit 7) then Monday (bit 6) through Saturday
+// (bit 1), schedule_weeks_of_month runs the first week (bit 5) through the
+// fifth (bit 1), and schedule_months runs January (bit 12) through December
+// (bit 1). So every day is 254, weekdays are 124 and every month is 8190.
+type PolicySchedule struct {
+	// Days the policy runs on
+	Days []time.Weekday
+	// Weeks of the month the policy runs in, 1 to 5. Week 1 is the first
+	// to seventh of the month, week 5 the 29th onwards. Empty means every
+	// week.
+	Weeks []int
+	// Months the policy runs in. Empty means every month.
+	Months []time.Month
+	// Hour and Minute the policy runs at
+	Hour   int
+	Minute int
+	// UTC is true when the schedule is in UTC rather than the device's
+	// local time, from the policy's use_scheduled_timezone setting
+	UTC bool
+}
+
+// DecodePolicySchedule decodes the raw schedule fields of a policy
+func DecodePolicySchedule(days, weeks, months int, at string, utc bool) (PolicySchedule, error) {
+	s := PolicySchedule{UTC: utc}
+
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if days&dayBit(wd) != 0 {
+			s.Days = append(s.Days, wd)
+		}
+	}
+	for w := 1; w <= 5; w++ {
+		if weeks&weekBit(w) != 0 {
+			s.Weeks = append(s.Weeks, w)
+		}
+	}
+	for m := time.January; m <= time.December; m++ {
+		if months&monthBit(m) != 0 {
+			s.Months = append(s.Months, m)
+		}
+	}
+
+	if at != "" {
+		if _, err := fmt.Sscanf(at, "%d:%d", &s.Hour, &s.Minute); err != nil {
+			return s, fmt.Errorf("invalid schedule time %q: %w", at, err)
+		}
+		if s.Hour < 0 || s.Hour > 23 || s.Minute < 0 || s.Minute > 59 {
+			return s, fmt.Errorf("invalid schedule time %q", at)
+		}
+	}
+
+	return s, nil
+}
+
+// Encode returns the raw schedule fields for the schedule, the inverse of
+// DecodePolicySchedule. Empty Weeks and Months encode as every week and
+// every month.
+func (s PolicySchedule) Encode() (days, weeks, months int, at string) {
+	for _, wd := range s.Days {
+		days |= dayBit(wd)
+	}
+
+	if len(s.Weeks) == 0 {
+		weeks = 62
+	}
+	for _, w := range s.Weeks {
+		weeks |= weekBit(w)
+	}
+
+	if len(s.Months) == 0 {
+		months = 8190
+	}
+	for _, m := range s.Months {
+		months |= monthBit(m)
+	}
+
+	return days, weeks, months, fmt.Sprintf("%02d:%02d", s.Hour, s.Minute)
+}
+
+// Next returns the first time the schedule runs strictly after the given
+// time, or the zero time if it never runs. tz is the device's local time
+// zone and is ignored for UTC schedules; nil is treated as UTC. A time
+// skipped as the clocks go forward runs once they have, and one repeated
+// as they go back runs the first time.
+func (s PolicySchedule) Next(after time.Time, tz *time.Location) time.Time {
+	loc := tz
+	if s.UTC || loc == nil {
+		loc = time.UTC
+	}
+
+	a := after.In(loc)
+	day := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, loc)
+	for i := 0; i < scheduleSearchDays; i++ {
+		d := day.AddDate(0, 0, i)
+		if !s.runsOn(d) {
+			continue
+		}
+		t := time.Date(d.Year(), d.Month(), d.Day(), s.Hour, s.Minute, 0, 0, loc)
+		if t.Hour() != s.Hour || t.Minute() != s.Minute {
+			// The time is skipped as the clocks go forward, and time.Date
+			// may have moved it back before the gap, so run once they have
+			t = d.Add(time.Duration(s.Hour)*time.Hour + time.Duration(s.Minute)*time.Minute)
+		}
+		if t.After(after) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Between returns every time the schedule runs after from and up to and
+// including to
+func (s PolicySchedule) Between(from, to time.Time, tz *time.Location) []time.Time {
+	var times []time.Time
+	for t := s.Next(from, tz); !t.IsZero() && !t.After(to); t = s.Next(t, tz) {
+		times = append(times, t)
+	}
+	return times
+}
+
+// runsOn reports whether the schedule runs on the day of d
+func (s PolicySchedule) runsOn(d time.Time) bool {
+	if !containsWeekday(s.Days, d.Weekday()) {
+		return false
+	}
+	if len(s.Weeks) > 0 && !containsInt(s.Weeks, (d.Day()-1)/7+1) {
+		return false
+	}
+	if len(s.Months) > 0 && !containsMonth(s.Months, d.Month()) {
+		return false
+	}
+	return true
+}
+
+func (s PolicySchedule) String() string {
+	days := make([]string, 0, len(s.Days))
+	for _, wd := range s.Days {
+		days = append(days, wd.String()[:3])
+	}
+
+	weeks := "every week"
+	if len(s.Weeks) > 0 {
+		w := make([]string, 0, len(s.Weeks))
+		for _, n := range s.Weeks {
+			w = append(w, fmt.Sprint(n))
+		}
+		weeks = "weeks " + strings.Join(w, ",")
+	}
+
+	months := "every month"
+	if len(s.Months) > 0 {
+		m := make([]string, 0, len(s.Months))
+		for _, n := range s.Months {
+			m = append(m, n.String()[:3])
+		}
+		months = strings.Join(m, ",")
+	}
+
+	zone := "device local time"
+	if s.UTC {
+		zone = "UTC"
+	}
+
+	return fmt.Sprintf("%s at %02d:%02d %s, %s of %s", strings.Join(days, ","), s.Hour, s.Minute, zone, weeks, months)
+}
+
+// Schedule decodes the policy's schedule
+func (p ServerPolicies) Schedule() (PolicySchedule, error) {
+	return DecodePolicySchedule(p.ScheduleDays, p.ScheduleWeeksOfMonth, p.ScheduleMonths, p.ScheduleTime, p.Configuration.UseScheduledTimezone)
+}
+
+// Schedule decodes the policy's schedule
+func (p Policy) Schedule() (PolicySchedule, error) {
+	var cfg struct {
+		UseScheduledTimezone bool `json:"use_scheduled_timezone"`
+	}
+	if len(p.Configuration) > 0 {
+		if err := json.Unmarshal(p.Configuration, &cfg); err != nil {
+			return PolicySchedule{}, err
+		}
+	}
+	return DecodePolicySchedule(p.ScheduleDays, p.ScheduleWeeksOfMonth, p.ScheduleMonths, p.ScheduleTime, cfg.UseScheduledTimezone)
+}
+
+// SetSchedule sets the policy's raw schedule fields from s. Whether the
+// schedule is in UTC lives in the policy configuration and is left as is.
+func (p *Policy) SetSchedule(s PolicySchedule) {
+	p.ScheduleDays, p.ScheduleWeeksOfMonth, p.ScheduleMonths, p.ScheduleTime = s.Encode()
+}
+
+func dayBit(wd time.Weekday) int {
+	if wd == time.Sunday {
+		return 1 << 7
+	}
+	return 1 << (7 - int(wd))
+}
+
+func weekBit(w int) int {
+	if w < 1 || w > 5 {
+		return 0
+	}
+	return 1 << (6 - w)
+}
+
+func monthBit(m time.Month) int {
+	return 1 << (13 - int(m))
+}
+
+func containsWeekday(list []time.Weekday, v time.Weekday) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsMonth(list []time.Month, v time.Month) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package automox
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+func TestDecodePolicySchedule(t *testing.T) {
+	everyDay := []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+	everyWeek := []int{1, 2, 3, 4, 5}
+	everyMonth := []time.Month{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+
+	for _, tt := range []struct {
+		name                string
+		days, weeks, months int
+		at                  string
+		want                PolicySchedule
+	}{
+		{"every day", 254, 62, 8190, "02:30", PolicySchedule{Days: everyDay, Weeks: everyWeek, Months: everyMonth, Hour: 2, Minute: 30}},
+		{"weekdays", 124, 62, 8190, "00:00", PolicySchedule{Days: weekdays, Weeks: everyWeek, Months: everyMonth}},
+		{"weekends", 130, 62, 8190, "23:59", PolicySchedule{Days: []time.Weekday{time.Sunday, time.Saturday}, Weeks: everyWeek, Months: everyMonth, Hour: 23, Minute: 59}},
+		{"third week", 64, 8, 8190, "12:00", PolicySchedule{Days: []time.Weekday{time.Monday}, Weeks: []int{3}, Months: everyMonth, Hour: 12}},
+		{"first and last weeks", 2, 34, 8190, "12:00", PolicySchedule{Days: []time.Weekday{time.Saturday}, Weeks: []int{1, 5}, Months: everyMonth, Hour: 12}},
+		{"January and December", 254, 62, 4098, "06:15", PolicySchedule{Days: everyDay, Weeks: everyWeek, Months: []time.Month{time.January, time.December}, Hour: 6, Minute: 15}},
+		{"unused bits ignored", 255, 63, 8191, "00:00", PolicySchedule{Days: everyDay, Weeks: everyWeek, Months: everyMonth}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodePolicySchedule(tt.days, tt.weeks, tt.months, tt.at, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %+v, want %+v", got, tt.want)
+			}
+
+			days, weeks, months, at := got.Encode()
+			if days != tt.days&254 || weeks != tt.weeks&62 || months != tt.months&8190 || at != tt.at {
+				t.Errorf("encoded %d, %d, %d, %q", days, weeks, months, at)
+			}
+		})
+	}
+
+	for _, at := range []string{"24:00", "12:60", "noon", "-1:00"} {
+		if _, err := DecodePolicySchedule(254, 62, 8190, at, false); err == nil {
+			t.Errorf("schedule time %q accepted", at)
+		}
+	}
+
+	// Empty weeks and months encode as every week and month
+	if _, weeks, months, _ := (PolicySchedule{Days: weekdays}).Encode(); weeks != 62 || months != 8190 {
+		t.Errorf("empty weeks and months encoded as %d and %d", weeks, months)
+	}
+}
+
+func TestPolicyScheduleNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	utc := func(s string) time.Time {
+		t.Helper()
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	for _, tt := range []struct {
+		name  string
+		s     PolicySchedule
+		after time.Time
+		tz    *time.Location
+		want  time.Time
+	}{
+		{
+			name:  "later the same day",
+			s:     PolicySchedule{Days: weekdays, Hour: 14},
+			after: utc("2026-01-05T09:00:00Z"),
+			want:  utc("2026-01-05T14:00:00Z"),
+		},
+		{
+			name:  "strictly after",
+			s:     PolicySchedule{Days: weekdays, Hour: 14},
+			after: utc("2026-01-05T14:00:00Z"),
+			want:  utc("2026-01-06T14:00:00Z"),
+		},
+		{
+			name:  "over the weekend",
+			s:     PolicySchedule{Days: weekdays, Hour: 2},
+			after: utc("2026-01-09T03:00:00Z"),
+			want:  utc("2026-01-12T02:00:00Z"),
+		},
+		{
+			name:  "into the next month",
+			s:     PolicySchedule{Days: []time.Weekday{time.Monday}, Weeks: []int{1}},
+			after: utc("2026-01-06T00:00:00Z"),
+			want:  utc("2026-02-02T00:00:00Z"),
+		},
+		{
+			name:  "into the next year",
+			s:     PolicySchedule{Days: weekdays, Months: []time.Month{time.January}, Hour: 1},
+			after: utc("2026-01-30T12:00:00Z"),
+			want:  utc("2027-01-01T01:00:00Z"),
+		},
+		{
+			name:  "fifth week of February",
+			s:     PolicySchedule{Days: []time.Weekday{time.Tuesday}, Weeks: []int{5}, Months: []time.Month{time.February}},
+			after: utc("2026-01-01T00:00:00Z"),
+			want:  utc("2028-02-29T00:00:00Z"),
+		},
+		{
+			name:  "never",
+			s:     PolicySchedule{Hour: 1},
+			after: utc("2026-01-01T00:00:00Z"),
+		},
+		{
+			name:  "device local time",
+			s:     PolicySchedule{Days: []time.Weekday{time.Monday}},
+			after: utc("2026-01-05T01:00:00Z"), // Sunday evening in New York
+			tz:    ny,
+			want:  time.Date(2026, 1, 5, 0, 0, 0, 0, ny),
+		},
+		{
+			name:  "UTC ignores the device's time zone",
+			s:     PolicySchedule{Days: []time.Weekday{time.Monday}, UTC: true},
+			after: utc("2026-01-05T01:00:00Z"),
+			tz:    ny,
+			want:  utc("2026-01-12T00:00:00Z"),
+		},
+		{
+			name:  "spring forward skips the missing hour",
+			s:     PolicySchedule{Days: []time.Weekday{time.Sunday}, Hour: 2, Minute: 30},
+			after: time.Date(2026, 3, 8, 0, 0, 0, 0, ny),
+			tz:    ny,
+			want:  time.Date(2026, 3, 8, 3, 30, 0, 0, ny),
+		},
+		{
+			name:  "fall back runs at the first of the repeated hour",
+			s:     PolicySchedule{Days: []time.Weekday{time.Sunday}, Hour: 1, Minute: 30},
+			after: time.Date(2026, 11, 1, 0, 0, 0, 0, ny),
+			tz:    ny,
+			want:  utc("2026-11-01T05:30:00Z"),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.s.Next(tt.after, tt.tz)
+			if !got.Equal(tt.want) {
+				t.Errorf("Next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// The repeated hour as DST ends runs only once
+	s := PolicySchedule{Days: []time.Weekday{time.Sunday, time.Monday}, Hour: 1, Minute: 30}
+	got := s.Between(time.Date(2026, 10, 31, 0, 0, 0, 0, ny), time.Date(2026, 11, 2, 12, 0, 0, 0, ny), ny)
+	want := []time.Time{utc("2026-11-01T05:30:00Z"), utc("2026-11-02T06:30:00Z")}
+	if len(got) != len(want) || !got[0].Equal(want[0]) || !got[1].Equal(want[1]) {
+		t.Errorf("Between = %v, want %v", got, want)
+	}
+}
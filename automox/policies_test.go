@@ -0,0 +1,61 @@
+package automox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUploadFileStreamsMultipart(t *testing.T) {
+	const content = "MZ installer bytes"
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/policies/7/files" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		f, hdr, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("reading form file: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		b, _ := io.ReadAll(f)
+		if hdr.Filename != "agent.msi" || string(b) != content {
+			t.Errorf("uploaded %s = %q", hdr.Filename, b)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := am.Policies().UploadFile(context.Background(), 7, "agent.msi", strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUploadFileReadError(t *testing.T) {
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	errRead := errors.New("disk gone")
+	r := io.MultiReader(strings.NewReader("partial"), &faultyBody{r: strings.NewReader(""), readErr: errRead})
+	if err := am.Policies().UploadFile(context.Background(), 7, "agent.msi", r); !errors.Is(err, errRead) {
+		t.Fatalf("error = %v, want the read error", err)
+	}
+}
+
+func TestRequiredSoftwarePolicyMissingInstaller(t *testing.T) {
+	b := NewRequiredSoftwarePolicy("Acme Agent").
+		Package("Acme Agent", "1.4.2").
+		Installer(filepath.Join(t.TempDir(), "missing.msi")).
+		InstallCommand(OSWindows, "msiexec /i missing.msi /qn")
+
+	if _, err := b.Build(); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("error = %v, want fs.ErrNotExist", err)
+	}
+}
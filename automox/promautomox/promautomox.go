@@ -0,0 +1,91 @@
+// Package promautomox exports metrics about the Automox API client's use
+// of the API to Prometheus.
+//
+//	metrics := promautomox.NewCollector()
+//	prometheus.MustRegister(metrics)
+//
+//	api, err := automox.New(ctx, token, nil, automox.WithMetrics(metrics))
+package promautomox
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rk295/go-automox/automox"
+)
+
+const namespace = "automox_client"
+
+// Collector is an automox.Metrics which exposes what it observes as
+// Prometheus metrics. It must be registered with a registry to be scraped.
+type Collector struct {
+	requests    *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+	rateLimited *prometheus.CounterVec
+	limiterWait prometheus.Histogram
+}
+
+var _ automox.Metrics = (*Collector)(nil)
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector returns a collector for a single client. Register one
+// collector per registry; clients may share it.
+func NewCollector() *Collector {
+	labels := []string{"route", "method", "status_class"}
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Attempts at Automox API requests, by route, method and status class.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of Automox API request attempts.",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+		}, labels),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limited_total",
+			Help:      "Automox API responses with status 429, by route and method.",
+		}, []string{"route", "method"}),
+		limiterWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rate_limiter_wait_seconds",
+			Help:      "Time request attempts spent waiting on the client rate limiter.",
+			Buckets:   []float64{0, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30},
+		}),
+	}
+}
+
+// ObserveRequest implements automox.Metrics
+func (c *Collector) ObserveRequest(route, method string, status int, duration time.Duration) {
+	class := automox.StatusClass(status)
+	c.requests.WithLabelValues(route, method, class).Inc()
+	c.duration.WithLabelValues(route, method, class).Observe(duration.Seconds())
+	if status == http.StatusTooManyRequests {
+		c.rateLimited.WithLabelValues(route, method).Inc()
+	}
+}
+
+// ObserveRateLimitWait implements automox.Metrics
+func (c *Collector) ObserveRateLimitWait(wait time.Duration) {
+	c.limiterWait.Observe(wait.Seconds())
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.duration.Describe(ch)
+	c.rateLimited.Describe(ch)
+	c.limiterWait.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.duration.Collect(ch)
+	c.rateLimited.Collect(ch)
+	c.limiterWait.Collect(ch)
+}
@@ -0,0 +1,71 @@
+package promautomox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rk295/go-automox/automox"
+)
+
+func TestCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/servers/2/") {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `[]`)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := NewCollector()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(metrics)
+
+	api, err := automox.New(context.Background(), "test-token", srv.Client(), automox.WithBaseURL(u), automox.WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := api.Servers()
+	if _, err := servers.GetPackages(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	_, err = servers.GetPackages(context.Background(), 2)
+	var res *automox.ErrorResponse
+	if !errors.As(err, &res) || res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("error = %v, want a 429", err)
+	}
+
+	const route = "/api/servers/{id}/packages"
+	for class, want := range map[string]float64{"2xx": 1, "4xx": 1} {
+		got := testutil.ToFloat64(metrics.requests.WithLabelValues(route, http.MethodGet, class))
+		if got != want {
+			t.Errorf("requests_total{status_class=%q} = %v, want %v", class, got, want)
+		}
+	}
+
+	expected := `
+# HELP automox_client_rate_limited_total Automox API responses with status 429, by route and method.
+# TYPE automox_client_rate_limited_total counter
+automox_client_rate_limited_total{method="GET",route="/api/servers/{id}/packages"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "automox_client_rate_limited_total"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(metrics, "automox_client_request_duration_seconds"); n != 2 {
+		t.Errorf("request_duration_seconds has %d series, want 2", n)
+	}
+}
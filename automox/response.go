@@ -0,0 +1,138 @@
+package automox
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Response is the metadata of the last response to a call, as captured by
+// CaptureResponse
+type Response struct {
+	// StatusCode is the HTTP status of the final attempt
+	StatusCode int
+	// RequestID is the ID Automox assigned the request, for support cases
+	RequestID string
+	// RateLimitRemaining is how many requests are left in the current rate
+	// limit window, or -1 when Automox did not say
+	RateLimitRemaining int
+	// RateLimitReset is when the rate limit window resets, or zero when
+	// Automox did not say. A 429's Retry-After is used when present.
+	RateLimitReset time.Time
+	// Total is the number of items across all pages of a listing, or -1
+	// when the endpoint does not report one
+	Total int
+	// Attempts is how many attempts the call took, counting retries. It is
+	// zero for a call answered from the cache without a request.
+	Attempts int
+	// FromCache is whether the response came from the cache. A fresh cache
+	// hit makes no request, so RequestID and the rate limit fields are left
+	// unknown rather than replayed from the stored response; a response
+	// revalidated with a 304 reports the 304's.
+	FromCache bool
+	// Header holds the response headers, which are those stored with a
+	// cached response
+	Header http.Header
+}
+
+// rateLimitHeaders are checked in order for the requests remaining and the
+// reset time of the rate limit window
+var rateLimitHeaders = []struct{ remaining, reset string }{
+	{"X-RateLimit-Remaining", "X-RateLimit-Reset"},
+	{"RateLimit-Remaining", "RateLimit-Reset"},
+}
+
+// totalHeaders are checked in order for the total size of a paged listing
+var totalHeaders = []string{"X-Total-Count", "X-Total"}
+
+// responseKey is the context key holding the *Response to fill
+type responseKey struct{}
+
+// CaptureResponse returns a context whose calls fill r with the metadata of
+// their response, including error responses. A call making several requests,
+// such as ListEach or an audit iterator, leaves the last one. Concurrent
+// calls must each be given their own Response.
+//
+//	var meta automox.Response
+//	servers, err := am.Servers().List(automox.CaptureResponse(ctx, &meta))
+//	if meta.RateLimitRemaining >= 0 && meta.RateLimitRemaining < 10 {
+//		time.Sleep(time.Until(meta.RateLimitReset))
+//	}
+func CaptureResponse(ctx context.Context, r *Response) context.Context {
+	return context.WithValue(ctx, responseKey{}, r)
+}
+
+// pageTotaler is implemented by paged envelopes reporting the total number
+// of items, which is preferred to any header
+type pageTotaler interface {
+	pageTotal() int
+}
+
+// captureResponse fills the context's Response, if any, from res
+func captureResponse(ctx context.Context, res *http.Response) {
+	meta, _ := ctx.Value(responseKey{}).(*Response)
+	if meta == nil {
+		return
+	}
+
+	*meta = Response{
+		StatusCode:         res.StatusCode,
+		RateLimitRemaining: -1,
+		Total:              -1,
+		Attempts:           1,
+		Header:             res.Header,
+	}
+	if call := CallFromContext(ctx); call != nil {
+		meta.Attempts = call.Attempts
+		meta.FromCache = call.FromCache
+	}
+	for _, h := range totalHeaders {
+		if n, err := strconv.Atoi(res.Header.Get(h)); err == nil {
+			meta.Total = n
+			break
+		}
+	}
+	// The stored headers of a fresh cache hit describe an earlier request
+	if meta.FromCache && meta.Attempts == 0 {
+		return
+	}
+
+	meta.RequestID = responseRequestID(res)
+
+	for _, h := range rateLimitHeaders {
+		if n, err := strconv.Atoi(res.Header.Get(h.remaining)); err == nil {
+			meta.RateLimitRemaining = n
+			meta.RateLimitReset = parseReset(res.Header.Get(h.reset))
+			break
+		}
+	}
+	if res.StatusCode == http.StatusTooManyRequests {
+		if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && s >= 0 {
+			meta.RateLimitRemaining = 0
+			meta.RateLimitReset = time.Now().Add(time.Duration(s) * time.Second)
+		}
+	}
+}
+
+// captureTotal records the total reported by a decoded paged envelope
+func captureTotal(ctx context.Context, v interface{}) {
+	meta, _ := ctx.Value(responseKey{}).(*Response)
+	if p, ok := v.(pageTotaler); ok && meta != nil {
+		meta.Total = p.pageTotal()
+	}
+}
+
+// parseReset reads a rate limit reset header, which is either a Unix time
+// or a number of seconds from now
+func parseReset(v string) time.Time {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return time.Time{}
+	}
+	// Anything past 2001 is taken as a Unix time rather than a delay
+	if n > 1e9 {
+		return time.Unix(n, 0)
+	}
+	return time.Now().Add(time.Duration(n) * time.Second)
+}
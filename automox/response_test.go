@@ -0,0 +1,86 @@
+package automox
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCaptureResponse(t *testing.T) {
+	var requests atomic.Int32
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		w.Header().Set("X-Request-Id", "req-"+strconv.Itoa(int(n)))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(100-int(n)))
+		w.Header().Set("X-RateLimit-Reset", "30")
+		w.Header().Set("X-Total-Count", "42")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `[]`)
+	}, WithCache(CacheConfig{TTL: 50 * time.Millisecond}))
+
+	call := func() Response {
+		t.Helper()
+		var meta Response
+		if _, err := am.Servers().List(CaptureResponse(context.Background(), &meta)); err != nil {
+			t.Fatal(err)
+		}
+		return meta
+	}
+
+	meta := call()
+	if meta.StatusCode != http.StatusOK || meta.RequestID != "req-1" || meta.RateLimitRemaining != 99 ||
+		meta.Total != 42 || meta.Attempts != 1 || meta.FromCache {
+		t.Errorf("first call: %+v", meta)
+	}
+	if d := time.Until(meta.RateLimitReset); d < 25*time.Second || d > 30*time.Second {
+		t.Errorf("rate limit resets in %v, want about 30s", d)
+	}
+
+	// A fresh hit makes no request, so has no request ID or rate limit
+	meta = call()
+	if requests.Load() != 1 {
+		t.Fatalf("cache hit made a request")
+	}
+	if meta.Attempts != 0 || !meta.FromCache || meta.RequestID != "" || meta.RateLimitRemaining != -1 ||
+		!meta.RateLimitReset.IsZero() || meta.Total != 42 {
+		t.Errorf("cache hit: %+v", meta)
+	}
+
+	// A revalidated response reports the 304's request ID and rate limit
+	time.Sleep(60 * time.Millisecond)
+	meta = call()
+	if requests.Load() != 2 {
+		t.Fatalf("expired entry was not revalidated")
+	}
+	if meta.Attempts != 1 || !meta.FromCache || meta.StatusCode != http.StatusOK || meta.RequestID != "req-2" || meta.RateLimitRemaining != 98 {
+		t.Errorf("revalidated: %+v", meta)
+	}
+}
+
+func TestCaptureResponseRateLimited(t *testing.T) {
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	var meta Response
+	_, err := am.Servers().List(CaptureResponse(context.Background(), &meta))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if meta.StatusCode != http.StatusTooManyRequests || meta.RateLimitRemaining != 0 || meta.Total != -1 {
+		t.Errorf("429: %+v", meta)
+	}
+	if d := time.Until(meta.RateLimitReset); d < 6*time.Second || d > 7*time.Second {
+		t.Errorf("rate limit resets in %v, want about 7s", d)
+	}
+}
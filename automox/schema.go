@@ -0,0 +1,325 @@
+package automox
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of SchemaWarning
+const (
+	// FieldUnknown is a field in the payload with no struct field to hold it
+	FieldUnknown = "unknown"
+	// FieldMistyped is a field whose JSON type does not suit its struct field
+	FieldMistyped = "mistyped"
+)
+
+// SchemaWarning is a difference between an API payload and the struct it
+// is decoded into, a sign that the Automox API has drifted
+type SchemaWarning struct {
+	// Route is the templated route of the call, empty from DiffSchema
+	Route string
+	// Path locates the field in the payload, with [] for any array element
+	// and * for any map entry, such as [].details.PS_VERSION
+	Path string
+	// Kind is FieldUnknown or FieldMistyped
+	Kind string
+	// Expected is the Go type of a mistyped field
+	Expected string
+	// Found is the JSON type of the payload's value
+	Found string
+}
+
+func (w SchemaWarning) String() string {
+	prefix := ""
+	if w.Route != "" {
+		prefix = w.Route + ": "
+	}
+	path := w.Path
+	if path == "" {
+		path = "the payload"
+	}
+	if w.Kind == FieldMistyped {
+		return fmt.Sprintf("%s%s is a JSON %s, expected %s", prefix, path, w.Found, w.Expected)
+	}
+	return fmt.Sprintf("%s%s is not a known field (JSON %s)", prefix, path, w.Found)
+}
+
+// WithStrictDecoding checks every decoded response against the struct it is
+// decoded into, passing any unknown or mistyped fields to hook. The call
+// still succeeds: unknown fields are dropped as usual, and a mistyped field
+// is left at its zero value rather than failing the call. Responses to
+// ListEach and StreamList, which are decoded as they stream, are not
+// checked.
+func WithStrictDecoding(hook func(context.Context, []SchemaWarning)) Option {
+	return func(c *Client) {
+		c.schemaHook = hook
+	}
+}
+
+// DiffSchema reports the fields of a JSON payload which v, a pointer to the
+// type it is decoded into, has no field for or cannot hold. It suits
+// checking a live payload in a nightly job or a test.
+func DiffSchema(data []byte, v interface{}) ([]SchemaWarning, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var payload interface{}
+	if err := d.Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	var warnings []SchemaWarning
+	seen := map[string]bool{}
+	diffSchema(payload, reflect.TypeOf(v), "", func(w SchemaWarning) {
+		key := w.Kind + " " + w.Path
+		if !seen[key] {
+			seen[key] = true
+			warnings = append(warnings, w)
+		}
+	})
+	sort.Slice(warnings, func(i, j int) bool { return warnings[i].Path < warnings[j].Path })
+	return warnings, nil
+}
+
+// decodeStrict decodes a response body into v, reporting any differences
+// between the payload and v to the strict decoding hook
+func (am *Client) decodeStrict(ctx context.Context, body []byte, v interface{}) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	// A mistyped field stops only itself being decoded, so the error is
+	// left for the warnings to report
+	decodeErr := json.Unmarshal(body, v)
+	if _, ok := decodeErr.(*json.UnmarshalTypeError); decodeErr != nil && !ok {
+		return decodeErr
+	}
+
+	warnings, err := DiffSchema(body, v)
+	if err != nil {
+		return err
+	}
+	// A decoding error the warnings do not account for must not be lost
+	if len(warnings) == 0 {
+		return decodeErr
+	}
+	// A payload of the wrong type altogether leaves nothing decoded, which
+	// is a failure rather than drift
+	if warnings[0].Path == "" {
+		return decodeErr
+	}
+	if call := CallFromContext(ctx); call != nil {
+		for i := range warnings {
+			warnings[i].Route = call.Route
+		}
+	}
+	am.schemaHook(ctx, warnings)
+	return nil
+}
+
+var (
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// diffSchema walks a decoded payload alongside the type it decodes into
+func diffSchema(v interface{}, t reflect.Type, path string, warn func(SchemaWarning)) {
+	if v == nil || t == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Types decoding themselves accept whatever they choose to
+	if reflect.PointerTo(t).Implements(jsonUnmarshaler) {
+		return
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshaler) {
+		if _, ok := v.(string); !ok {
+			warn(mistyped(path, t, v))
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return
+
+	case reflect.Struct:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			warn(mistyped(path, t, v))
+			return
+		}
+		fields := jsonFields(t)
+		for key, child := range obj {
+			f, ok := fields.lookup(key)
+			if !ok {
+				warn(SchemaWarning{Path: joinPath(path, key), Kind: FieldUnknown, Found: jsonType(child)})
+				continue
+			}
+			if f.quoted {
+				if _, ok := child.(string); ok {
+					continue
+				}
+			}
+			diffSchema(child, f.typ, joinPath(path, key), warn)
+		}
+
+	case reflect.Map:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			warn(mistyped(path, t, v))
+			return
+		}
+		for _, child := range obj {
+			diffSchema(child, t.Elem(), joinPath(path, "*"), warn)
+		}
+
+	case reflect.Slice, reflect.Array:
+		// Byte slices are sent base64 encoded
+		if t.Elem().Kind() == reflect.Uint8 {
+			if _, ok := v.(string); !ok {
+				warn(mistyped(path, t, v))
+			}
+			return
+		}
+		arr, ok := v.([]interface{})
+		if !ok {
+			warn(mistyped(path, t, v))
+			return
+		}
+		for _, child := range arr {
+			diffSchema(child, t.Elem(), path+"[]", warn)
+		}
+
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			warn(mistyped(path, t, v))
+		}
+
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			warn(mistyped(path, t, v))
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := v.(json.Number)
+		if !ok {
+			warn(mistyped(path, t, v))
+		} else if _, err := strconv.ParseInt(n.String(), 10, t.Bits()); err != nil {
+			// Fractions and numbers too large for the field alike
+			warn(mistyped(path, t, v))
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := v.(json.Number)
+		if !ok {
+			warn(mistyped(path, t, v))
+		} else if _, err := strconv.ParseUint(n.String(), 10, t.Bits()); err != nil {
+			warn(mistyped(path, t, v))
+		}
+
+	case reflect.Float32, reflect.Float64:
+		n, ok := v.(json.Number)
+		if !ok {
+			warn(mistyped(path, t, v))
+		} else if _, err := strconv.ParseFloat(n.String(), t.Bits()); err != nil {
+			warn(mistyped(path, t, v))
+		}
+	}
+}
+
+// jsonField is a struct field as encoding/json sees it
+type jsonField struct {
+	typ    reflect.Type
+	quoted bool
+}
+
+// fieldSet holds the JSON fields of a struct by name
+type fieldSet map[string]jsonField
+
+// lookup finds the field a key decodes into, preferring an exact match and
+// otherwise matching case insensitively, as encoding/json does
+func (fs fieldSet) lookup(key string) (jsonField, bool) {
+	if f, ok := fs[key]; ok {
+		return f, true
+	}
+	for name, f := range fs {
+		if strings.EqualFold(name, key) {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+// jsonFields returns the JSON fields of struct type t, including those
+// promoted from embedded structs
+func jsonFields(t reflect.Type) fieldSet {
+	fields := fieldSet{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := sf.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for n, f := range jsonFields(ft) {
+				if _, ok := fields[n]; !ok {
+					fields[n] = f
+				}
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields[name] = jsonField{typ: sf.Type, quoted: strings.Contains(opts, "string")}
+	}
+	return fields
+}
+
+func mistyped(path string, t reflect.Type, v interface{}) SchemaWarning {
+	return SchemaWarning{Path: path, Kind: FieldMistyped, Expected: t.String(), Found: jsonType(v)}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case json.Number:
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
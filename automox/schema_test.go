@@ -0,0 +1,153 @@
+package automox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type schemaDevice struct {
+	ID    int64          `json:"id"`
+	Port  uint16         `json:"port"`
+	Load  float32        `json:"load"`
+	Name  string         `json:"name"`
+	Tags  []string       `json:"tags"`
+	Nics  []schemaNic    `json:"nics"`
+	Extra interface{}    `json:"extra"`
+	Seen  AutomoxTime    `json:"seen"`
+	Count int            `json:"count,string"`
+	Meta  map[string]int `json:"meta"`
+}
+
+type schemaNic struct {
+	Mac string   `json:"mac"`
+	Ips []string `json:"ips"`
+}
+
+func TestDiffSchema(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		data string
+		v    interface{}
+		want []SchemaWarning
+	}{
+		{
+			name: "matching",
+			data: `{"id":1,"port":443,"load":0.5,"name":"a","tags":["x"],"nics":[{"mac":"m","ips":["i"]}],"extra":{"any":[1]},"count":"3","meta":{"a":1}}`,
+			v:    &schemaDevice{},
+		},
+		{
+			name: "unknown fields",
+			data: `{"id":1,"os_family":"Linux","nics":[{"mac":"m","vendor":"acme"}]}`,
+			v:    &schemaDevice{},
+			want: []SchemaWarning{
+				{Path: "nics[].vendor", Kind: FieldUnknown, Found: "string"},
+				{Path: "os_family", Kind: FieldUnknown, Found: "string"},
+			},
+		},
+		{
+			name: "mistyped fields",
+			data: `{"id":"1","name":7,"tags":"x","load":true}`,
+			v:    &schemaDevice{},
+			want: []SchemaWarning{
+				{Path: "id", Kind: FieldMistyped, Expected: "int64", Found: "string"},
+				{Path: "load", Kind: FieldMistyped, Expected: "float32", Found: "bool"},
+				{Path: "name", Kind: FieldMistyped, Expected: "string", Found: "number"},
+				{Path: "tags", Kind: FieldMistyped, Expected: "[]string", Found: "string"},
+			},
+		},
+		{
+			name: "overflow and fractions",
+			data: `{"id":99999999999999999999,"port":70000,"count":"2","meta":{"a":1.5}}`,
+			v:    &schemaDevice{},
+			want: []SchemaWarning{
+				{Path: "id", Kind: FieldMistyped, Expected: "int64", Found: "number"},
+				{Path: "meta.*", Kind: FieldMistyped, Expected: "int", Found: "number"},
+				{Path: "port", Kind: FieldMistyped, Expected: "uint16", Found: "number"},
+			},
+		},
+		{
+			name: "nested arrays",
+			data: `{"nics":[{"mac":"a","ips":["1"]},{"mac":2,"ips":[3]},{"mac":"c","ips":null}]}`,
+			v:    &schemaDevice{},
+			want: []SchemaWarning{
+				{Path: "nics[].ips[]", Kind: FieldMistyped, Expected: "string", Found: "number"},
+				{Path: "nics[].mac", Kind: FieldMistyped, Expected: "string", Found: "number"},
+			},
+		},
+		{
+			name: "wrong top-level type",
+			data: `{"data":[]}`,
+			v:    &Servers{},
+			want: []SchemaWarning{
+				{Path: "", Kind: FieldMistyped, Expected: "automox.Servers", Found: "object"},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffSchema([]byte(tt.data), tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("warnings:\n got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// failingField always fails to decode, without the schema knowing why
+type failingField struct{}
+
+func (*failingField) UnmarshalJSON([]byte) error {
+	return &json.UnmarshalTypeError{Value: "string", Type: reflect.TypeOf(failingField{})}
+}
+
+func TestWithStrictDecoding(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		body     string
+		v        interface{}
+		warnings int
+		err      bool
+	}{
+		{name: "clean", body: `{"id":1}`, v: &schemaDevice{}},
+		{name: "unknown field", body: `{"id":1,"new":true}`, v: &schemaDevice{}, warnings: 1},
+		{name: "overflow", body: `{"id":99999999999999999999}`, v: &schemaDevice{}, warnings: 1},
+		{name: "wrong top-level type", body: `{"id":1}`, v: &Servers{}, err: true},
+		{name: "unexplained type error", body: `{"f":"x"}`, v: &struct {
+			F failingField `json:"f"`
+		}{}, err: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []SchemaWarning
+			am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = io.WriteString(w, tt.body)
+			}, WithStrictDecoding(func(ctx context.Context, w []SchemaWarning) {
+				got = append(got, w...)
+			}))
+
+			_, err := am.Do(context.Background(), http.MethodGet, "/api/servers/1", nil, nil, tt.v)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v, want error %v", err, tt.err)
+			}
+			var typeErr *json.UnmarshalTypeError
+			if tt.err && !errors.As(err, &typeErr) {
+				t.Errorf("error = %v, want a *json.UnmarshalTypeError", err)
+			}
+			if len(got) != tt.warnings {
+				t.Errorf("warnings = %v, want %d", got, tt.warnings)
+			}
+			for _, w := range got {
+				if w.Route != "/api/servers/{id}" {
+					t.Errorf("warning route = %q", w.Route)
+				}
+			}
+		})
+	}
+}
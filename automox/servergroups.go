@@ -0,0 +1,33 @@
+package automox
+
+import (
+	"context"
+	"net/http"
+)
+
+const serverGroupsURL = "/api/servergroups"
+
+// ServerGroupsService is an interface for interacting with the server group
+// endpoints of the Automox API
+type ServerGroupsService interface {
+	List(context.Context) (ServerGroups, error)
+}
+
+// ServerGroupsClient facilitates requests with the Automox server groups
+type ServerGroupsClient struct {
+	client *Client
+}
+
+// List all server groups in the organization
+func (c *ServerGroupsClient) List(ctx context.Context) (ServerGroups, error) {
+	req, err := c.client.newRequest(ctx, http.MethodGet, serverGroupsURL, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ServerGroups{}
+	if _, err := c.client.makeRequest(req, res); err != nil {
+		return nil, err
+	}
+	return *res, nil
+}
@@ -0,0 +1,30 @@
+package automox
+
+type ServerGroups []ServerGroup
+
+// ServerGroup is a group of devices which policies are assigned to
+type ServerGroup struct {
+	ID                  int    `json:"id"`
+	OrganizationID      int    `json:"organization_id"`
+	Name                string `json:"name"`
+	Notes               string `json:"notes"`
+	ParentServerGroupID int    `json:"parent_server_group_id"`
+	RefreshInterval     int    `json:"refresh_interval"`
+	ServerCount         int    `json:"server_count"`
+	Policies            []int  `json:"policies"`
+	UIColor             string `json:"ui_color"`
+}
+
+// Names maps each group's ID to its name. The default group has no name in
+// the API, so it is reported as "Default".
+func (g ServerGroups) Names() map[int]string {
+	names := make(map[int]string, len(g))
+	for _, group := range g {
+		name := group.Name
+		if name == "" && group.ParentServerGroupID == 0 {
+			name = "Default"
+		}
+		names[group.ID] = name
+	}
+	return names
+}
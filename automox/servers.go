@@ -4,20 +4,31 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"net/url"
 	"strings"
 	"text/tabwriter"
+
+	"github.com/rk295/go-automox/automox/internal/shared"
 )
 
-const serversURL = "/api/servers"
+const (
+	serversURL   = "/api/servers"
+	inventoryURL = "/api/device-details/orgs/%s/devices/%s/inventory"
+)
 
 // ServersService is an interface for interacting with the server endpoints
 // of the Automox API
 type ServersService interface {
 	List(context.Context) (Servers, error)
+	ListEach(context.Context, func(ServerDetails) error) error
+	StreamList(context.Context) <-chan ServerResult
 	Get(context.Context, int64) (*ServerDetails, error)
 	GetPackages(context.Context, int64) (*Packages, error)
 	GetCommandQueue(context.Context, int64) (*CommandQueue, error)
+	Inventory(context.Context, string) (*Inventory, error)
+	AddTags(context.Context, int64, ...string) ([]string, error)
+	RemoveTags(context.Context, int64, ...string) ([]string, error)
+	SetTags(context.Context, int64, ...string) ([]string, error)
+	ListTags(context.Context) ([]TagCount, error)
 }
 
 // ServersClient facilitates requests with the Automox servers
@@ -27,14 +38,7 @@ type ServersClient struct {
 
 // List all tasks assigned to a given ticket ID
 func (c *ServersClient) List(ctx context.Context) (Servers, error) {
-	// TODO: Here....
-	url := &url.URL{
-		Scheme: "https",
-		Host:   c.client.apiURL,
-		Path:   serversURL,
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	req, err := c.client.newRequest(ctx, http.MethodGet, serversURL, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -48,15 +52,61 @@ func (c *ServersClient) List(ctx context.Context) (Servers, error) {
 	return *res, nil
 }
 
-// Get a specific Server ticket by Server ID.
-func (c *ServersClient) Get(ctx context.Context, id int64) (*ServerDetails, error) {
-	url := &url.URL{
-		Scheme: "https",
-		Host:   c.client.apiURL,
-		Path:   fmt.Sprintf("%s/%d", serversURL, id),
+// ListEach lists all servers, calling fn with each one as it is decoded
+// rather than holding the whole list in memory. Listing stops at the first
+// error returned by fn, which ListEach then returns. The response is not
+// subject to the default size cap, only to one set with
+// WithMaxResponseSize.
+func (c *ServersClient) ListEach(ctx context.Context, fn func(ServerDetails) error) error {
+	req, err := c.client.newRequest(ctx, http.MethodGet, serversURL, nil, nil)
+	if err != nil {
+		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	_, err = c.client.makeRequest(req, eachServer(fn))
+	return err
+}
+
+// StreamList lists all servers, sending each one on the returned channel as
+// it is decoded. A failure is sent as a final result with Err set. The
+// channel is closed when listing ends and must be read until then, or ctx
+// cancelled.
+func (c *ServersClient) StreamList(ctx context.Context) <-chan ServerResult {
+	return streamServers(ctx, c)
+}
+
+func init() {
+	shared.StreamServers = streamServers
+}
+
+// streamServers streams the servers listed by svc.ListEach over a channel,
+// as ServersService.StreamList does, for implementations of the service
+// such as fakes
+func streamServers(ctx context.Context, svc ServersService) <-chan ServerResult {
+	out := make(chan ServerResult)
+	go func() {
+		defer close(out)
+		err := svc.ListEach(ctx, func(s ServerDetails) error {
+			select {
+			case out <- ServerResult{ID: int64(s.ID), Server: &s}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			select {
+			case out <- ServerResult{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out
+}
+
+// Get a specific Server ticket by Server ID.
+func (c *ServersClient) Get(ctx context.Context, id int64) (*ServerDetails, error) {
+	req, err := c.client.newRequest(ctx, http.MethodGet, fmt.Sprintf("%s/%d", serversURL, id), nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -70,13 +120,7 @@ func (c *ServersClient) Get(ctx context.Context, id int64) (*ServerDetails, erro
 
 // GetPackages retrieves the list of packages installed on a server
 func (c *ServersClient) GetPackages(ctx context.Context, id int64) (*Packages, error) {
-	url := &url.URL{
-		Scheme: "https",
-		Host:   c.client.apiURL,
-		Path:   fmt.Sprintf("%s/%d/packages", serversURL, id),
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	req, err := c.client.newRequest(ctx, http.MethodGet, fmt.Sprintf("%s/%d/packages", serversURL, id), nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -90,18 +134,31 @@ func (c *ServersClient) GetPackages(ctx context.Context, id int64) (*Packages, e
 
 // GetCommandQueue returns the queue of upcoming commands for the specified device
 func (c *ServersClient) GetCommandQueue(ctx context.Context, id int64) (*CommandQueue, error) {
-	url := &url.URL{
-		Scheme: "https",
-		Host:   c.client.apiURL,
-		Path:   fmt.Sprintf("%s/%d/queues", serversURL, id),
+	req, err := c.client.newRequest(ctx, http.MethodGet, fmt.Sprintf("%s/%d/queues", serversURL, id), nil, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	res := &CommandQueue{}
+	if _, err := c.client.makeRequest(req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Inventory retrieves the hardware and software inventory for the device
+// with the given UUID, as found in ServerDetails.UUID
+func (c *ServersClient) Inventory(ctx context.Context, uuid string) (*Inventory, error) {
+	if c.client.orgUUID == "" {
+		return nil, missingOptionErr("organization UUID")
+	}
+
+	req, err := c.client.newRequest(ctx, http.MethodGet, fmt.Sprintf(inventoryURL, c.client.orgUUID, uuid), nil, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	res := &CommandQueue{}
+	res := &Inventory{}
 	if _, err := c.client.makeRequest(req, res); err != nil {
 		return nil, err
 	}
@@ -1,6 +1,9 @@
 package automox
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"time"
@@ -45,6 +48,14 @@ func (at *AutomoxTime) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+func (at AutomoxTime) MarshalJSON() ([]byte, error) {
+	t := time.Time(at)
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + t.Format(automoxTimeFormat) + `"`), nil
+}
+
 type Servers []ServerDetails
 
 // ServerDetails are the details related to a specific server in Automox
@@ -98,13 +109,57 @@ type ServerDetails struct {
 	ServerGroupID                 int                 `json:"server_group_id"`
 	ServerPolicies                []ServerPolicies    `json:"server_policies"`
 	Status                        Status              `json:"status"`
-	Tags                          []interface{}       `json:"tags"`
+	Tags                          []string            `json:"tags"`
 	Timezone                      string              `json:"timezone"`
 	TotalCount                    int                 `json:"total_count"`
 	Uptime                        AutomoxUptime       `json:"uptime"`
 	UUID                          string              `json:"uuid"`
 }
 
+// eachServer decodes a list of servers one element at a time, handing each
+// to the function as it goes
+type eachServer func(ServerDetails) error
+
+func (fn eachServer) decodeResponse(r io.Reader) error {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return fmt.Errorf("expected a list of servers, got %v", tok)
+	}
+
+	for dec.More() {
+		var s ServerDetails
+		if err := dec.Decode(&s); err != nil {
+			return err
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+// serverUpdateRequest is the body of a server update. Automox replaces the
+// whole record, so unchanged values must be sent back as they were read.
+type serverUpdateRequest struct {
+	ServerGroupID int      `json:"server_group_id"`
+	CustomName    string   `json:"custom_name"`
+	Exception     bool     `json:"exception"`
+	Tags          []string `json:"tags"`
+}
+
+// TagCount is a device tag and the number of devices carrying it
+type TagCount struct {
+	Tag     string
+	Devices int
+}
+
 type CompatibilityChecks struct {
 	AppStoreDisconnected bool `json:"app_store_disconnected"`
 	MissingSecureToken   bool `json:"missing_secure_token"`
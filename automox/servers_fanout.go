@@ -0,0 +1,175 @@
+package automox
+
+import (
+	"context"
+	"sync"
+)
+
+// ServerResult is the outcome of fetching a single server in a fan-out
+type ServerResult struct {
+	ID     int64
+	Server *ServerDetails
+	Err    error
+}
+
+// PackagesResult is the outcome of fetching a single server's packages in a
+// fan-out
+type PackagesResult struct {
+	ID       int64
+	Packages *Packages
+	Err      error
+}
+
+// CommandQueueResult is the outcome of fetching a single server's command
+// queue in a fan-out
+type CommandQueueResult struct {
+	ID    int64
+	Queue *CommandQueue
+	Err   error
+}
+
+// fanOutResult is the untyped result passed back by fanOut
+type fanOutResult struct {
+	id  int64
+	v   interface{}
+	err error
+}
+
+// GetForAll fetches every server in ids from svc using up to concurrency
+// requests at once, returning a result for every ID. svc may be any
+// ServersService, such as api.Servers() or a fake.
+func GetForAll(ctx context.Context, svc ServersService, ids []int64, concurrency int) map[int64]ServerResult {
+	results := make(map[int64]ServerResult, len(ids))
+	for r := range StreamGet(ctx, svc, ids, concurrency) {
+		results[r.ID] = r
+	}
+	return results
+}
+
+// StreamGet fetches every server in ids from svc using up to concurrency
+// requests at once, sending each result as it arrives. The channel is
+// closed once every ID has been handled; IDs not started before ctx is done
+// are sent with the context's error. The channel must be read until it is
+// closed.
+func StreamGet(ctx context.Context, svc ServersService, ids []int64, concurrency int) <-chan ServerResult {
+	out := make(chan ServerResult)
+	go func() {
+		defer close(out)
+		for r := range fanOut(ctx, ids, concurrency, func(ctx context.Context, id int64) (interface{}, error) {
+			return svc.Get(ctx, id)
+		}) {
+			res := ServerResult{ID: r.id, Err: r.err}
+			res.Server, _ = r.v.(*ServerDetails)
+			out <- res
+		}
+	}()
+	return out
+}
+
+// GetPackagesForAll fetches the packages of every server in ids from svc
+// using up to concurrency requests at once, returning a result for every ID
+func GetPackagesForAll(ctx context.Context, svc ServersService, ids []int64, concurrency int) map[int64]PackagesResult {
+	results := make(map[int64]PackagesResult, len(ids))
+	for r := range StreamPackages(ctx, svc, ids, concurrency) {
+		results[r.ID] = r
+	}
+	return results
+}
+
+// StreamPackages fetches the packages of every server in ids from svc using
+// up to concurrency requests at once, sending each result as it arrives. It
+// behaves as StreamGet does on cancellation.
+func StreamPackages(ctx context.Context, svc ServersService, ids []int64, concurrency int) <-chan PackagesResult {
+	out := make(chan PackagesResult)
+	go func() {
+		defer close(out)
+		for r := range fanOut(ctx, ids, concurrency, func(ctx context.Context, id int64) (interface{}, error) {
+			return svc.GetPackages(ctx, id)
+		}) {
+			res := PackagesResult{ID: r.id, Err: r.err}
+			res.Packages, _ = r.v.(*Packages)
+			out <- res
+		}
+	}()
+	return out
+}
+
+// GetCommandQueueForAll fetches the command queue of every server in ids
+// from svc using up to concurrency requests at once, returning a result for
+// every ID
+func GetCommandQueueForAll(ctx context.Context, svc ServersService, ids []int64, concurrency int) map[int64]CommandQueueResult {
+	results := make(map[int64]CommandQueueResult, len(ids))
+	for r := range StreamCommandQueues(ctx, svc, ids, concurrency) {
+		results[r.ID] = r
+	}
+	return results
+}
+
+// StreamCommandQueues fetches the command queue of every server in ids from
+// svc using up to concurrency requests at once, sending each result as it
+// arrives. It behaves as StreamGet does on cancellation.
+func StreamCommandQueues(ctx context.Context, svc ServersService, ids []int64, concurrency int) <-chan CommandQueueResult {
+	out := make(chan CommandQueueResult)
+	go func() {
+		defer close(out)
+		for r := range fanOut(ctx, ids, concurrency, func(ctx context.Context, id int64) (interface{}, error) {
+			return svc.GetCommandQueue(ctx, id)
+		}) {
+			res := CommandQueueResult{ID: r.id, Err: r.err}
+			res.Queue, _ = r.v.(*CommandQueue)
+			out <- res
+		}
+	}()
+	return out
+}
+
+// IDs returns the ID of every server, ready to pass to the fan-out helpers
+func (s Servers) IDs() []int64 {
+	ids := make([]int64, 0, len(s))
+	for _, server := range s {
+		ids = append(ids, int64(server.ID))
+	}
+	return ids
+}
+
+// fanOut calls fn for every ID with at most concurrency calls in flight.
+// Requests still pass through the client's rate limiter, so concurrency only
+// bounds how many may wait on it at once. Every ID gets exactly one result.
+func fanOut(ctx context.Context, ids []int64, concurrency int, fn func(context.Context, int64) (interface{}, error)) <-chan fanOutResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	jobs := make(chan int64)
+	out := make(chan fanOutResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				if err := ctx.Err(); err != nil {
+					out <- fanOutResult{id: id, err: err}
+					continue
+				}
+				v, err := fn(ctx, id)
+				out <- fanOutResult{id: id, v: v, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, id := range ids {
+			jobs <- id
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
@@ -0,0 +1,150 @@
+package automox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/rk295/go-automox/automox/internal/shared"
+)
+
+// tagUpdateAttempts is how many times a tag update is retried when another
+// writer changes the device's tags between our read and write
+const tagUpdateAttempts = 3
+
+// AddTags adds the given tags to a device, keeping any it already has
+func (c *ServersClient) AddTags(ctx context.Context, id int64, tags ...string) ([]string, error) {
+	return c.updateTags(ctx, id, func(current []string) []string {
+		return shared.MergeTags(current, tags, nil)
+	})
+}
+
+// RemoveTags removes the given tags from a device, keeping any others
+func (c *ServersClient) RemoveTags(ctx context.Context, id int64, tags ...string) ([]string, error) {
+	return c.updateTags(ctx, id, func(current []string) []string {
+		return shared.MergeTags(current, nil, tags)
+	})
+}
+
+// SetTags replaces every tag on a device with the given tags
+func (c *ServersClient) SetTags(ctx context.Context, id int64, tags ...string) ([]string, error) {
+	return c.updateTags(ctx, id, func([]string) []string {
+		return shared.MergeTags(nil, tags, nil)
+	})
+}
+
+// ListTags lists every distinct tag in the organization along with the
+// number of devices carrying it. Servers are counted as they are listed
+// rather than held in memory.
+func (c *ServersClient) ListTags(ctx context.Context) ([]TagCount, error) {
+	counts := tagCounts{}
+	err := c.ListEach(ctx, func(s ServerDetails) error {
+		counts.add(s.Tags)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts.sorted(), nil
+}
+
+// updateTags reads the device, applies modify to its tags and writes them
+// back. Automox has no conditional updates, so the device is read again
+// afterwards and the whole cycle retried if a concurrent writer clobbered
+// the change. Updates to the same device through this client are
+// serialised.
+func (c *ServersClient) updateTags(ctx context.Context, id int64, modify func([]string) []string) ([]string, error) {
+	unlock := c.client.lockDevice(id)
+	defer unlock()
+
+	// Reads must see the current tags, not a cached copy
+	ctx = SkipCache(ctx)
+
+	for attempt := 0; attempt < tagUpdateAttempts; attempt++ {
+		s, err := c.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		want := modify(s.Tags)
+		if sameTags(s.Tags, want) {
+			return want, nil
+		}
+
+		if err := c.update(ctx, id, serverUpdateRequest{
+			ServerGroupID: s.ServerGroupID,
+			CustomName:    s.CustomName,
+			Exception:     s.Exception,
+			Tags:          want,
+		}); err != nil {
+			return nil, err
+		}
+
+		s, err = c.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if sameTags(s.Tags, want) {
+			return want, nil
+		}
+	}
+
+	return nil, fmt.Errorf("tags on server %d were changed concurrently %d times, giving up", id, tagUpdateAttempts)
+}
+
+// update writes the given values to a device
+func (c *ServersClient) update(ctx context.Context, id int64, u serverUpdateRequest) error {
+	req, err := c.client.newRequest(ctx, http.MethodPut, fmt.Sprintf("%s/%d", serversURL, id), nil, u)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.makeRequest(req, nil)
+	return err
+}
+
+// Tags counts the devices carrying each distinct tag, sorted by tag
+func (s Servers) Tags() []TagCount {
+	counts := tagCounts{}
+	for _, server := range s {
+		counts.add(server.Tags)
+	}
+	return counts.sorted()
+}
+
+// tagCounts is the number of devices carrying each tag
+type tagCounts map[string]int
+
+// add counts one device carrying tags, each distinct tag once
+func (c tagCounts) add(tags []string) {
+	for _, tag := range shared.MergeTags(nil, tags, nil) {
+		c[tag]++
+	}
+}
+
+// sorted returns the counts sorted by tag
+func (c tagCounts) sorted() []TagCount {
+	tags := make([]TagCount, 0, len(c))
+	for tag, n := range c {
+		tags = append(tags, TagCount{Tag: tag, Devices: n})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags
+}
+
+// sameTags reports whether a and b hold the same set of tags
+func sameTags(a, b []string) bool {
+	a, b = shared.MergeTags(nil, a, nil), shared.MergeTags(nil, b, nil)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,318 @@
+package automox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const packagesPayload = `[{"id":1,"name":"openssl","version":"3.0.13","installed":true,"severity":"high","cves":["CVE-2024-0727"]},` +
+	`{"id":2,"name":"curl","version":"8.5.0","installed":true,"severity":"medium","cves":[]}]`
+
+// BenchmarkGetPackages compares fetching packages over the default
+// transport, which keeps connections alive, with a transport which opens a
+// new TLS connection for every request
+func BenchmarkGetPackages(b *testing.B) {
+	var conns atomic.Int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, packagesPayload)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	// Trust the test server's certificate
+	tlsConfig := srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+	for _, bm := range []struct {
+		name      string
+		keepAlive bool
+	}{
+		{"KeepAlive", true},
+		{"ForceClose", false},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			transport := defaultTransport()
+			transport.TLSClientConfig = tlsConfig.Clone()
+			transport.DisableKeepAlives = !bm.keepAlive
+			defer transport.CloseIdleConnections()
+
+			am, err := New(context.Background(), "test-token", &http.Client{Transport: transport}, WithBaseURL(u))
+			if err != nil {
+				b.Fatal(err)
+			}
+			servers := am.Servers()
+
+			conns.Store(0)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := servers.GetPackages(context.Background(), 1); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+		})
+	}
+}
+
+func TestLockDeviceReleasesLocks(t *testing.T) {
+	am, err := New(context.Background(), "test-token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	held := map[int64]*atomic.Int32{1: {}, 2: {}, 3: {}}
+	for i := 0; i < 60; i++ {
+		id := int64(i%3 + 1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := am.lockDevice(id)
+			defer unlock()
+			if n := held[id].Add(1); n != 1 {
+				t.Errorf("device %d held by %d updates at once", id, n)
+			}
+			time.Sleep(time.Millisecond)
+			held[id].Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	am.deviceLocksMu.Lock()
+	defer am.deviceLocksMu.Unlock()
+	if n := len(am.deviceLocks); n != 0 {
+		t.Errorf("%d device locks left after every update finished", n)
+	}
+}
+
+// serverList writes body as the response to a server list
+func serverList(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, body)
+	}
+}
+
+func TestListEachStreams(t *testing.T) {
+	// The second server is only written once the first has been handed to
+	// the callback, so the list cannot have been read in full beforehand
+	first := make(chan struct{})
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `[{"id":1,"name":"web-01"},`)
+		w.(http.Flusher).Flush()
+		select {
+		case <-first:
+		case <-time.After(5 * time.Second):
+			t.Error("the first server was not handed on until the list was read in full")
+		}
+		_, _ = io.WriteString(w, `{"id":2,"name":"web-02"}]`)
+	})
+
+	var names []string
+	err := am.Servers().ListEach(context.Background(), func(s ServerDetails) error {
+		if s.ID == 1 {
+			close(first)
+		}
+		names = append(names, s.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"web-01", "web-02"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("servers = %v, want %v", names, want)
+	}
+}
+
+func TestListEachStopsEarly(t *testing.T) {
+	am := newTestClient(t, serverList(`[{"id":1},{"id":2},{"id":3}]`))
+	errStop := errors.New("stop")
+
+	var ids []int
+	err := am.Servers().ListEach(context.Background(), func(s ServerDetails) error {
+		ids = append(ids, s.ID)
+		if s.ID == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("err = %v, want the callback's error", err)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("servers = %v, want %v", ids, want)
+	}
+}
+
+func TestListEachDecodeErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		body string
+		want []int
+	}{
+		{"not a list", `{"id":1}`, nil},
+		{"mistyped element", `[{"id":1},{"id":"two"},{"id":3}]`, []int{1}},
+		{"truncated", `[{"id":1},{"id":2`, []int{1}},
+		{"unterminated", `[{"id":1}`, []int{1}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			am := newTestClient(t, serverList(tt.body))
+			var ids []int
+			err := am.Servers().ListEach(context.Background(), func(s ServerDetails) error {
+				ids = append(ids, s.ID)
+				return nil
+			})
+			if err == nil {
+				t.Error("no error")
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("servers = %v before the error, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamList(t *testing.T) {
+	am := newTestClient(t, serverList(`[{"id":1},{"id":2},{"id":"three"}]`))
+
+	var got []string
+	for r := range am.Servers().StreamList(context.Background()) {
+		if r.Err != nil {
+			got = append(got, "error")
+			continue
+		}
+		if r.ID != int64(r.Server.ID) {
+			t.Errorf("result ID %d for server %d", r.ID, r.Server.ID)
+		}
+		got = append(got, fmt.Sprint(r.ID))
+	}
+	// The decode error ends the stream as its final result
+	if want := []string{"1", "2", "error"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestStreamListCancel(t *testing.T) {
+	am := newTestClient(t, serverList(`[{"id":1},{"id":2},{"id":3}]`))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := am.Servers().StreamList(ctx)
+	if r := <-results; r.Err != nil || r.ID != 1 {
+		t.Fatalf("first result = %+v", r)
+	}
+	cancel()
+
+	// Nothing need read the rest once ctx is cancelled, but the channel
+	// still closes
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-results:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("channel not closed after cancelling")
+		}
+	}
+}
+
+func TestListTags(t *testing.T) {
+	am := newTestClient(t, serverList(`[{"id":1,"tags":["prod","eu","prod"]},{"id":2,"tags":["eu",""]},{"id":3,"tags":null}]`))
+
+	tags, err := am.Servers().ListTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A tag repeated on one device counts that device once
+	want := []TagCount{{Tag: "eu", Devices: 2}, {Tag: "prod", Devices: 1}}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags = %+v, want %+v", tags, want)
+	}
+
+	am = newTestClient(t, serverList(`[{"id":1,"tags":["prod"]},{"id":"two"}]`))
+	if _, err := am.Servers().ListTags(context.Background()); err == nil {
+		t.Error("decode error not returned")
+	}
+}
+
+const inventoryPayload = `{
+	"hardware": {"manufacturer": "Dell Inc.", "serial_number": "5CG1234XYZ", "cpu": {"model": "Xeon", "cores": 8, "threads": 16}, "memory_bytes": 34359738368, "disks": [{"name": "sda", "size_bytes": 512110190592}]},
+	"network": {"hostname": "web-01", "dns_servers": ["10.0.0.2"], "interfaces": [{"name": "eth0", "mac": "00:11:22:33:44:55", "ipv4": ["10.0.0.10"], "connected": true}]},
+	"security": {"firewall": {"enabled": true, "profiles": [{"name": "Domain", "enabled": true}]}, "encryption": [{"volume": "C:", "method": "XTS-AES 128", "encrypted": true}], "antivirus": [{"name": "Defender", "enabled": true, "up_to_date": false}]},
+	"services": [{"name": "sshd", "status": "running", "start_type": "auto"}],
+	"users": [{"name": "admin", "is_admin": true}, {"name": "guest", "disabled": true}],
+	"software": [{"name": "curl", "version": "8.5.0", "publisher": "curl"}]
+}`
+
+func TestInventory(t *testing.T) {
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if want := "/api/device-details/orgs/org-uuid/devices/device-uuid/inventory"; r.URL.Path != want {
+			t.Errorf("path = %q, want %q", r.URL.Path, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, inventoryPayload)
+	}, WithOrgUUID("org-uuid"))
+
+	inv, err := am.Servers().Inventory(context.Background(), "device-uuid")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hw := inv.Hardware
+	if hw.Manufacturer != "Dell Inc." || hw.CPU.Threads != 16 || hw.MemoryBytes != 32<<30 || len(hw.Disks) != 1 || hw.Disks[0].SizeBytes != 512110190592 {
+		t.Errorf("hardware = %+v", hw)
+	}
+	if n := inv.Network; n.Hostname != "web-01" || len(n.Interfaces) != 1 || !n.Interfaces[0].Connected || !reflect.DeepEqual(n.Interfaces[0].IPv4, []string{"10.0.0.10"}) {
+		t.Errorf("network = %+v", n)
+	}
+	sec := inv.Security
+	if !sec.Firewall.Enabled || len(sec.Firewall.Profiles) != 1 || len(sec.Encryption) != 1 || !sec.Encryption[0].Encrypted {
+		t.Errorf("security = %+v", sec)
+	}
+	if len(sec.Antivirus) != 1 || !sec.Antivirus[0].Enabled || sec.Antivirus[0].UpToDate {
+		t.Errorf("antivirus = %+v", sec.Antivirus)
+	}
+	if len(inv.Services) != 1 || inv.Services[0].StartType != "auto" {
+		t.Errorf("services = %+v", inv.Services)
+	}
+	if len(inv.Users) != 2 || !inv.Users[0].IsAdmin || !inv.Users[1].Disabled {
+		t.Errorf("users = %+v", inv.Users)
+	}
+	if len(inv.Software) != 1 || inv.Software[0].Version != "8.5.0" {
+		t.Errorf("software = %+v", inv.Software)
+	}
+}
+
+func TestInventoryNeedsOrgUUID(t *testing.T) {
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL)
+	})
+	if _, err := am.Servers().Inventory(context.Background(), "device-uuid"); err == nil {
+		t.Error("inventory fetched without an organization UUID")
+	}
+}
@@ -0,0 +1,170 @@
+package automox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenSource supplies the API token. The client asks for it on every
+// request, so a source can hand out a new token whenever it is rotated.
+// Implementations must be safe for concurrent use.
+type TokenSource interface {
+	Token(context.Context) (string, error)
+}
+
+// TokenRefresher is implemented by token sources which cache their token
+// and can be told to fetch it afresh, as the client does after a 401
+type TokenRefresher interface {
+	RefreshToken(context.Context) error
+}
+
+// WithTokenSource has the client take its token from src on every request
+// in place of the token passed to New, which may then be empty
+func WithTokenSource(src TokenSource) Option {
+	return func(c *Client) {
+		c.tokens = src
+	}
+}
+
+// StaticToken is a TokenSource which always returns the same token
+type StaticToken string
+
+// Token implements TokenSource
+func (t StaticToken) Token(context.Context) (string, error) {
+	if t == "" {
+		return "", missingClientConfigErr("Token")
+	}
+	return string(t), nil
+}
+
+// EnvToken is a TokenSource which reads the token from the named
+// environment variable on every request
+type EnvToken string
+
+// Token implements TokenSource
+func (e EnvToken) Token(context.Context) (string, error) {
+	t := strings.TrimSpace(os.Getenv(string(e)))
+	if t == "" {
+		return "", fmt.Errorf("environment variable %s holding the Automox token is empty", string(e))
+	}
+	return t, nil
+}
+
+// FileToken is a TokenSource which reads the token from a file, such as a
+// mounted Kubernetes secret. The file is read again whenever its size or
+// modification time changes, so replacing it rotates the token.
+type FileToken struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+// NewFileToken returns a source reading the token from the file at path
+func NewFileToken(path string) *FileToken {
+	return &FileToken{path: path}
+}
+
+// Token implements TokenSource
+func (f *FileToken) Token(context.Context) (string, error) {
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.token != "" && fi.ModTime().Equal(f.modTime) && fi.Size() == f.size {
+		return f.token, nil
+	}
+	return f.read(fi)
+}
+
+// RefreshToken implements TokenRefresher, reading the file even if it does
+// not appear to have changed
+func (f *FileToken) RefreshToken(context.Context) error {
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err = f.read(fi)
+	return err
+}
+
+// read loads the token from the file described by fi. f.mu must be held.
+func (f *FileToken) read(fi os.FileInfo) (string, error) {
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", err
+	}
+	t := strings.TrimSpace(string(b))
+	if t == "" {
+		return "", fmt.Errorf("token file %s is empty", f.path)
+	}
+	f.token, f.modTime, f.size = t, fi.ModTime(), fi.Size()
+	return t, nil
+}
+
+// authorize is the built-in middleware which adds the token to each
+// attempt. A 401 has the token source refreshed and, if that yields a
+// different token, the request is sent once more with it as a new attempt.
+func (am *Client) authorize(next RoundTripFunc) RoundTripFunc {
+	return func(r *http.Request) (*http.Response, error) {
+		ctx := r.Context()
+		token, err := am.tokens.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("getting Automox token: %w", err)
+		}
+		am.lastToken.Store(token)
+		r.Header.Set("Authorization", "Bearer "+token)
+
+		res, err := next(r)
+		if err != nil || res.StatusCode != http.StatusUnauthorized {
+			return res, err
+		}
+
+		if ref, ok := am.tokens.(TokenRefresher); ok {
+			if err := ref.RefreshToken(ctx); err != nil {
+				return res, nil
+			}
+		}
+		fresh, err := am.tokens.Token(ctx)
+		if err != nil || fresh == token {
+			return res, nil
+		}
+
+		retry := r.Clone(nextAttempt(ctx))
+		if r.Body != nil && r.Body != http.NoBody {
+			if r.GetBody == nil {
+				return res, nil
+			}
+			if retry.Body, err = r.GetBody(); err != nil {
+				return res, nil
+			}
+		}
+		discardBody(res)
+
+		am.lastToken.Store(fresh)
+		retry.Header.Set("Authorization", "Bearer "+fresh)
+		return next(retry)
+	}
+}
+
+// currentToken returns the token most recently sent, for redaction
+func (am *Client) currentToken() string {
+	t, _ := am.lastToken.Load().(string)
+	if t == "" {
+		return am.Token
+	}
+	return t
+}
@@ -0,0 +1,194 @@
+package automox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStaticToken(t *testing.T) {
+	if _, err := StaticToken("").Token(context.Background()); err == nil {
+		t.Error("empty token accepted")
+	}
+	if got, err := StaticToken("abc").Token(context.Background()); err != nil || got != "abc" {
+		t.Errorf("Token = %q, %v", got, err)
+	}
+}
+
+func TestEnvTokenRotation(t *testing.T) {
+	src := EnvToken("AUTOMOX_TEST_TOKEN")
+	t.Setenv("AUTOMOX_TEST_TOKEN", "")
+	if _, err := src.Token(context.Background()); err == nil {
+		t.Error("empty variable accepted")
+	}
+
+	for _, want := range []string{"first", "second"} {
+		t.Setenv("AUTOMOX_TEST_TOKEN", " "+want+"\n")
+		if got, err := src.Token(context.Background()); err != nil || got != want {
+			t.Errorf("Token = %q, %v, want %q", got, err, want)
+		}
+	}
+}
+
+func TestFileTokenRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	write := func(token string, mod time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token := func(src *FileToken) string {
+		t.Helper()
+		got, err := src.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	mod := time.Now().Add(-time.Hour).Truncate(time.Second)
+	write("aaaa", mod)
+	src := NewFileToken(path)
+	if got := token(src); got != "aaaa" {
+		t.Errorf("Token = %q, want aaaa", got)
+	}
+
+	// A replaced file is read again
+	write("bbbb", mod.Add(time.Second))
+	if got := token(src); got != "bbbb" {
+		t.Errorf("after rewrite, Token = %q, want bbbb", got)
+	}
+
+	// A change which keeps the size and modification time goes unseen
+	// until the token is refreshed
+	write("cccc", mod.Add(time.Second))
+	if got := token(src); got != "bbbb" {
+		t.Errorf("unchanged file read again, Token = %q", got)
+	}
+	if err := src.RefreshToken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := token(src); got != "cccc" {
+		t.Errorf("after refresh, Token = %q, want cccc", got)
+	}
+
+	write("", mod.Add(2*time.Second))
+	if _, err := src.Token(context.Background()); err == nil {
+		t.Error("empty file accepted")
+	}
+}
+
+// rotatingToken hands out the current token, moving on to the next only
+// when refreshed
+type rotatingToken struct {
+	mu     sync.Mutex
+	tokens []string
+}
+
+func (s *rotatingToken) Token(context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[0], nil
+}
+
+func (s *rotatingToken) RefreshToken(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.tokens) > 1 {
+		s.tokens = s.tokens[1:]
+	}
+	return nil
+}
+
+func TestAuthorizeRefreshesOn401(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		valid    string
+		tokens   []string
+		body     func() interface{}
+		requests int
+		status   int
+	}{
+		{
+			name:     "refreshed token accepted",
+			valid:    "new",
+			tokens:   []string{"old", "new"},
+			requests: 2,
+			status:   http.StatusOK,
+		},
+		{
+			name:     "refreshed token rejected",
+			tokens:   []string{"old", "new", "newer"},
+			requests: 2,
+			status:   http.StatusUnauthorized,
+		},
+		{
+			name:     "token unchanged",
+			valid:    "new",
+			tokens:   []string{"old"},
+			requests: 1,
+			status:   http.StatusUnauthorized,
+		},
+		{
+			name:     "replayable body",
+			valid:    "new",
+			tokens:   []string{"old", "new"},
+			body:     func() interface{} { return map[string]string{"name": "a"} },
+			requests: 2,
+			status:   http.StatusOK,
+		},
+		{
+			name:     "non-replayable body",
+			valid:    "new",
+			tokens:   []string{"old", "new"},
+			body:     func() interface{} { return io.MultiReader(strings.NewReader(`{"name":"a"}`)) },
+			requests: 1,
+			status:   http.StatusUnauthorized,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var requests int
+			am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requests++
+				mu.Unlock()
+				if b, _ := io.ReadAll(r.Body); r.Method == http.MethodPost && string(b) != `{"name":"a"}` {
+					t.Errorf("body = %q", b)
+				}
+				if r.Header.Get("Authorization") != "Bearer "+tt.valid {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}, WithTokenSource(&rotatingToken{tokens: tt.tokens}))
+
+			method, body := http.MethodGet, interface{}(nil)
+			if tt.body != nil {
+				method, body = http.MethodPost, tt.body()
+			}
+			_, err := am.Do(context.Background(), method, "/api/servers", nil, body, nil)
+			var errRes *ErrorResponse
+			switch {
+			case tt.status == http.StatusOK && err != nil:
+				t.Fatalf("Do: %v", err)
+			case tt.status != http.StatusOK && (!errors.As(err, &errRes) || errRes.StatusCode != tt.status):
+				t.Fatalf("error = %v, want a %d", err, tt.status)
+			}
+			if requests != tt.requests {
+				t.Errorf("%d requests sent, want %d", requests, tt.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package automox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/rk295/go-automox/automox/internal/shared"
+)
+
+const (
+	actionSetsURL = "/api/orgs/%d/remediations/action-sets"
+	// solutionsPageSize is how many solutions are fetched per request
+	solutionsPageSize = 500
+)
+
+// VulnSyncService is an interface for interacting with the Vulnerability
+// Sync endpoints of the Automox API
+type VulnSyncService interface {
+	UploadCSV(context.Context, string, io.Reader) (*ActionSet, error)
+	UploadFindings(context.Context, []Finding) (*ActionSet, error)
+	ListActionSets(context.Context) (ActionSets, error)
+	GetActionSet(context.Context, int64) (*ActionSet, error)
+	ListSolutions(context.Context, int64) (ActionSetSolutions, error)
+	Execute(context.Context, int64, []RemediationAction) error
+}
+
+// VulnSyncClient facilitates requests with the Automox Vulnerability Sync
+// endpoints
+type VulnSyncClient struct {
+	client *Client
+}
+
+// actionSetsPath returns the action sets path for the client's organization
+func (c *VulnSyncClient) actionSetsPath() (string, error) {
+	if c.client.orgID == 0 {
+		return "", missingOptionErr("organization ID")
+	}
+	return fmt.Sprintf(actionSetsURL, c.client.orgID), nil
+}
+
+// UploadCSV uploads a scanner CSV export as a new action set. The filename
+// is passed on to Automox and shown in the console.
+func (c *VulnSyncClient) UploadCSV(ctx context.Context, filename string, r io.Reader) (*ActionSet, error) {
+	path, err := c.actionSetsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := c.client.newRequest(ctx, http.MethodPost, path+"/upload", nil, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	res := &ActionSet{}
+	if _, err := c.client.makeRequest(req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UploadFindings uploads a structured list of findings as a new action set,
+// encoding them in the same CSV layout accepted by UploadCSV
+func (c *VulnSyncClient) UploadFindings(ctx context.Context, findings []Finding) (*ActionSet, error) {
+	rows := make([]shared.Finding, len(findings))
+	for i, f := range findings {
+		rows[i] = shared.Finding(f)
+	}
+	b, err := shared.EncodeFindings(rows)
+	if err != nil {
+		return nil, err
+	}
+	return c.UploadCSV(ctx, shared.FindingsFilename, bytes.NewReader(b))
+}
+
+// ListActionSets lists every action set in the organization
+func (c *VulnSyncClient) ListActionSets(ctx context.Context) (ActionSets, error) {
+	path, err := c.actionSetsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.client.newRequest(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ActionSets{}
+	if _, err := c.client.makeRequest(req, res); err != nil {
+		return nil, err
+	}
+	return *res, nil
+}
+
+// GetActionSet retrieves a single action set by ID
+func (c *VulnSyncClient) GetActionSet(ctx context.Context, id int64) (*ActionSet, error) {
+	path, err := c.actionSetsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.client.newRequest(ctx, http.MethodGet, fmt.Sprintf("%s/%d", path, id), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ActionSet{}
+	if _, err := c.client.makeRequest(req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListSolutions lists the remediations Automox matched for an action set,
+// along with the devices each one applies to, fetching every page
+func (c *VulnSyncClient) ListSolutions(ctx context.Context, id int64) (ActionSetSolutions, error) {
+	path, err := c.actionSetsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var solutions ActionSetSolutions
+	for page := 0; ; page++ {
+		q := url.Values{}
+		q.Set("page", strconv.Itoa(page))
+		q.Set("limit", strconv.Itoa(solutionsPageSize))
+
+		req, err := c.client.newRequest(ctx, http.MethodGet, fmt.Sprintf("%s/%d/solutions", path, id), q, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		res := &actionSetSolutionsPage{}
+		if _, err := c.client.makeRequest(req, res); err != nil {
+			return nil, err
+		}
+		solutions = append(solutions, res.Data...)
+
+		// Size is the total across every page; an empty page also ends
+		// the listing in case the total changes while paging
+		if len(solutions) >= res.Size || len(res.Data) == 0 {
+			return solutions, nil
+		}
+	}
+}
+
+// Execute carries out the given remediation actions for an action set
+func (c *VulnSyncClient) Execute(ctx context.Context, id int64, actions []RemediationAction) error {
+	path, err := c.actionSetsPath()
+	if err != nil {
+		return err
+	}
+
+	body := remediationActionsRequest{Actions: actions}
+	req, err := c.client.newRequest(ctx, http.MethodPost, fmt.Sprintf("%s/%d/actions", path, id), nil, body)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.makeRequest(req, nil)
+	return err
+}
+
+// SuggestedActions returns an action for every step of every solution,
+// targeting all of the solution's matched devices
+func (s ActionSetSolutions) SuggestedActions() []RemediationAction {
+	var actions []RemediationAction
+	for _, sol := range s {
+		for _, step := range sol.Actions {
+			actions = append(actions, RemediationAction{
+				Action:     step.Action,
+				SolutionID: sol.ID,
+			})
+		}
+	}
+	return actions
+}
+
+// Matches reports whether the solution remediates any of the CVEs fixed by
+// the given package
+func (s ActionSetSolution) Matches(p PackageDetails) bool {
+	for _, a := range s.Cves {
+		for _, b := range p.Cves {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CVEs returns the distinct CVEs across all of the packages
+func (p Packages) CVEs() []string {
+	seen := map[string]bool{}
+	var cves []string
+	for _, pkg := range p {
+		for _, cve := range pkg.Cves {
+			if !seen[cve] {
+				seen[cve] = true
+				cves = append(cves, cve)
+			}
+		}
+	}
+	return cves
+}
+
+// FindingsFromPackages builds a finding for every CVE of every package on
+// the server which is not yet installed, ready for UploadFindings
+func FindingsFromPackages(s ServerDetails, pkgs Packages) []Finding {
+	var ip string
+	if len(s.IPAddrs) > 0 {
+		ip = s.IPAddrs[0]
+	}
+
+	var findings []Finding
+	for _, pkg := range pkgs {
+		if pkg.Installed {
+			continue
+		}
+		for _, cve := range pkg.Cves {
+			findings = append(findings, Finding{
+				Hostname:  s.Name,
+				IPAddress: ip,
+				Cve:       cve,
+				Severity:  pkg.Severity,
+				Title:     pkg.DisplayName,
+			})
+		}
+	}
+	return findings
+}
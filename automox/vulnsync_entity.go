@@ -0,0 +1,109 @@
+package automox
+
+type ActionSets []ActionSet
+
+// ActionSet is a batch of vulnerability findings uploaded to Vulnerability
+// Sync, along with the remediations Automox matched against them
+type ActionSet struct {
+	ID             int64               `json:"id"`
+	OrganizationID int64               `json:"organization_id"`
+	Status         string              `json:"status"`
+	Source         ActionSetSource     `json:"source"`
+	CreatedBy      ActionSetUser       `json:"created_by"`
+	CreatedAt      AutomoxTime         `json:"created_at"`
+	UpdatedAt      AutomoxTime         `json:"updated_at"`
+	Statistics     ActionSetStatistics `json:"statistics"`
+}
+
+type ActionSetSource struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type ActionSetUser struct {
+	ID        int64  `json:"id"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Email     string `json:"email"`
+}
+
+type ActionSetStatistics struct {
+	Issues    ActionSetIssueStatistics    `json:"issues"`
+	Solutions ActionSetSolutionStatistics `json:"solutions"`
+}
+
+type ActionSetIssueStatistics struct {
+	Total     int `json:"total"`
+	Matched   int `json:"matched"`
+	Unmatched int `json:"unmatched"`
+}
+
+type ActionSetSolutionStatistics struct {
+	Total           int `json:"total"`
+	DevicesImpacted int `json:"devices_impacted"`
+	Vulnerabilities int `json:"vulnerabilities"`
+}
+
+type ActionSetSolutions []ActionSetSolution
+
+// ActionSetSolution is a remediation Automox suggests for one or more of
+// the CVEs in an action set, and the devices it applies to
+type ActionSetSolution struct {
+	ID       int64                   `json:"id"`
+	Title    string                  `json:"title"`
+	Severity string                  `json:"severity"`
+	Cves     []string                `json:"cves"`
+	Devices  []ActionSetDevice       `json:"devices"`
+	Actions  []ActionSetSolutionStep `json:"actions"`
+	Software ActionSetSoftware       `json:"software"`
+}
+
+type ActionSetDevice struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	OsFamily string `json:"os_family"`
+}
+
+type ActionSetSolutionStep struct {
+	Action string `json:"action"`
+}
+
+type ActionSetSoftware struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	PackageID int64  `json:"package_id"`
+}
+
+// actionSetSolutionsPage is the paged envelope returned by the solutions
+// endpoint
+type actionSetSolutionsPage struct {
+	Data ActionSetSolutions `json:"data"`
+	Size int                `json:"size"`
+}
+
+func (p *actionSetSolutionsPage) pageTotal() int {
+	return p.Size
+}
+
+// Finding is a single vulnerability reported by an external scanner for a
+// host, as uploaded to Vulnerability Sync
+type Finding struct {
+	Hostname  string
+	IPAddress string
+	Cve       string
+	Severity  string
+	Title     string
+}
+
+// RemediationAction requests that Automox carries out a solution's action
+// on the given devices. Leaving Devices empty targets every matched device.
+type RemediationAction struct {
+	Action     string  `json:"action"`
+	SolutionID int64   `json:"solution_id"`
+	Devices    []int64 `json:"devices,omitempty"`
+}
+
+type remediationActionsRequest struct {
+	Actions []RemediationAction `json:"actions"`
+}
@@ -0,0 +1,44 @@
+package automox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestListSolutionsPages(t *testing.T) {
+	const total, perPage = 5, 2
+	var pages []int
+	am := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/orgs/9/remediations/action-sets/3/solutions" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		pages = append(pages, page)
+
+		res := actionSetSolutionsPage{Size: total}
+		for id := page*perPage + 1; id <= total && id <= (page+1)*perPage; id++ {
+			res.Data = append(res.Data, ActionSetSolution{ID: int64(id)})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	}, WithOrgID(9))
+
+	solutions, err := am.VulnSync().ListSolutions(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(solutions) != total {
+		t.Fatalf("got %d solutions, want %d", len(solutions), total)
+	}
+	for i, s := range solutions {
+		if s.ID != int64(i+1) {
+			t.Errorf("solution %d has ID %d", i, s.ID)
+		}
+	}
+	if len(pages) != 3 || pages[0] != 0 || pages[2] != 2 {
+		t.Errorf("fetched pages %v, want [0 1 2]", pages)
+	}
+}
@@ -0,0 +1,100 @@
+// Command fakeautomox serves a fake Automox API from in-memory state, for
+// demos and for running tooling against something realistic offline.
+//
+//	fakeautomox -addr :8080 -token secret -servers 50
+//	fakeautomox -fixtures fixtures.json -rate 10
+//
+// Fixtures are the JSON encoding of automoxtest.Fixtures. Without them the
+// server is seeded with generated demo devices. Point a client at it with
+// automox.WithBaseURL.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rk295/go-automox/automox"
+	"github.com/rk295/go-automox/automox/automoxtest"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	token := flag.String("token", "", "API token clients must send; empty accepts any")
+	rate := flag.Int("rate", 0, "requests allowed per second before 429s; 0 for no limit")
+	fixtures := flag.String("fixtures", "", "JSON file of fixtures to serve")
+	servers := flag.Int("servers", 25, "number of demo servers to generate when no fixtures are given")
+	flag.Parse()
+
+	fx := demoFixtures(*servers)
+	if *fixtures != "" {
+		var err error
+		if fx, err = loadFixtures(*fixtures); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	h := automoxtest.NewHandler(automoxtest.New(fx), automoxtest.ServerConfig{
+		Token:     *token,
+		RateLimit: *rate,
+	})
+	log.Printf("fake Automox API listening on http://%s with %d servers", *addr, len(fx.Servers))
+	log.Fatal(http.ListenAndServe(*addr, h))
+}
+
+// loadFixtures reads fixtures from a JSON file
+func loadFixtures(path string) (automoxtest.Fixtures, error) {
+	var fx automoxtest.Fixtures
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fx, err
+	}
+	if err := json.Unmarshal(b, &fx); err != nil {
+		return fx, fmt.Errorf("reading fixtures from %s: %w", path, err)
+	}
+	return fx, nil
+}
+
+// demoFixtures generates n servers across a few groups, each with some
+// packages and a queued command
+func demoFixtures(n int) automoxtest.Fixtures {
+	fx := automoxtest.Fixtures{
+		ServerGroups: automox.ServerGroups{
+			{ID: 1},
+			{ID: 2, Name: "Production", ParentServerGroupID: 1},
+			{ID: 3, Name: "Staging", ParentServerGroupID: 1},
+		},
+		Packages:      map[int64]automox.Packages{},
+		CommandQueues: map[int64]automox.CommandQueue{},
+	}
+
+	osFamilies := []string{automox.OSWindows, automox.OSMac, automox.OSLinux}
+	now := time.Now()
+	for i := 1; i <= n; i++ {
+		family := osFamilies[i%len(osFamilies)]
+		group := 2 + i%2
+		fx.ServerGroups[group-1].ServerCount++
+
+		fx.Servers = append(fx.Servers, automox.ServerDetails{
+			ID:              i,
+			Name:            fmt.Sprintf("demo-%03d", i),
+			OsFamily:        family,
+			ServerGroupID:   group,
+			IPAddrs:         []string{fmt.Sprintf("10.0.%d.%d", i/250, i%250+1)},
+			Tags:            []string{"demo"},
+			LastRefreshTime: automox.AutomoxTime(now.Add(-time.Duration(i) * time.Minute)),
+		})
+		fx.Packages[int64(i)] = automox.Packages{
+			{ID: int64(i*10 + 1), Name: "openssl", DisplayName: "OpenSSL", Installed: true},
+			{ID: int64(i*10 + 2), Name: "curl", DisplayName: "curl", Cves: []string{"CVE-2023-38545"}, Severity: "critical"},
+		}
+		fx.CommandQueues[int64(i)] = automox.CommandQueue{
+			{CommandTypeName: "GetOS", ExecTime: automox.AutomoxTime(now)},
+		}
+	}
+	return fx
+}